@@ -0,0 +1,255 @@
+package zip2jsons
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+)
+
+// AvroCodec is the block compression of an Avro object container file.
+type AvroCodec string
+
+// Avro codecs.
+const (
+	AvroNull    AvroCodec = "null"
+	AvroDeflate AvroCodec = "deflate"
+)
+
+// ParseAvroCodec parses the name of an AvroCodec.
+func ParseAvroCodec(s string) (AvroCodec, error) {
+	switch c := AvroCodec(s); c {
+	case AvroNull, AvroDeflate:
+		return c, nil
+	default:
+		return "", fmt.Errorf("unknown avro codec: %s", s)
+	}
+}
+
+// AvroSchema is the schema of the records written by AvroWriter.
+const AvroSchema = `{"type":"record","name":"Blob","namespace":"zip2jsons","fields":[` +
+	`{"name":"name","type":"string"},` +
+	`{"name":"body","type":"bytes"},` +
+	`{"name":"content_type","type":"string"},` +
+	`{"name":"content_encoding","type":"string"},` +
+	`{"name":"length","type":["null","long"],"default":null},` +
+	`{"name":"modified","type":["null",{"type":"long","logicalType":"timestamp-millis"}],"default":null},` +
+	`{"name":"metadata","type":{"type":"map","values":"string"}}` +
+	`]}`
+
+// avroSyncInterval is the default size of the uncompressed blocks.
+const avroSyncInterval = 64000
+
+var avroMagic = []byte("Obj\x01")
+
+type avroBuffer struct{ bytes.Buffer }
+
+func (a *avroBuffer) putLong(v int64) {
+	a.Write(binary.AppendVarint(nil, v))
+}
+
+func (a *avroBuffer) putBytes(b []byte) {
+	a.putLong(int64(len(b)))
+	a.Write(b)
+}
+
+func (a *avroBuffer) putString(s string) {
+	a.putLong(int64(len(s)))
+	a.WriteString(s)
+}
+
+// stringMap writes the map as a single block followed by the end marker.
+func (a *avroBuffer) putStringMap(keys []string, values map[string]string) {
+	if 0 < len(keys) {
+		a.putLong(int64(len(keys)))
+		for _, key := range keys {
+			a.putString(key)
+			a.putString(values[key])
+		}
+	}
+	a.putLong(0)
+}
+
+// stringMetadata converts the metadata to strings, keeping the JSON text of non-string values.
+func stringMetadata(raw json.RawMessage) (map[string]string, error) {
+	var meta map[string]json.RawMessage
+	if 0 < len(raw) {
+		e := json.Unmarshal(raw, &meta)
+		if nil != e {
+			return nil, fmt.Errorf("could not decode metadata: %w", e)
+		}
+	}
+
+	var converted map[string]string = make(map[string]string, len(meta))
+	for key, val := range meta {
+		var s string
+		if nil == json.Unmarshal(val, &s) {
+			converted[key] = s
+			continue
+		}
+		converted[key] = string(val)
+	}
+	return converted, nil
+}
+
+// AvroWriter writes the blobs as an Avro object container file with the AvroSchema.
+// The sink must be closed with CloseSink to write the last block.
+type AvroWriter struct {
+	io.Writer
+
+	// Codec defaults to AvroNull.
+	Codec AvroCodec
+
+	// SyncInterval is the approximate size of the uncompressed blocks. Defaults to 64000 bytes.
+	SyncInterval int
+
+	started bool
+	sync    [16]byte
+	block   avroBuffer
+	count   int64
+	out     avroBuffer
+}
+
+func (a *AvroWriter) codec() AvroCodec {
+	if "" == a.Codec {
+		return AvroNull
+	}
+	return a.Codec
+}
+
+func (a *AvroWriter) writeHeader() error {
+	_, e := rand.Read(a.sync[:])
+	if nil != e {
+		return fmt.Errorf("could not create sync marker: %w", e)
+	}
+
+	a.out.Reset()
+	a.out.Write(avroMagic)
+	a.out.putStringMap(
+		[]string{"avro.codec", "avro.schema"},
+		map[string]string{"avro.codec": string(a.codec()), "avro.schema": AvroSchema},
+	)
+	a.out.Write(a.sync[:])
+
+	_, e = a.Writer.Write(a.out.Bytes())
+	if nil != e {
+		return fmt.Errorf("could not write avro header: %w", e)
+	}
+	a.started = true
+	return nil
+}
+
+func (a *AvroWriter) encode(b *bj.Blob) error {
+	body, e := blobBody(b)
+	if nil != e {
+		return e
+	}
+
+	meta, e := stringMetadata(b.Metadata)
+	if nil != e {
+		return fmt.Errorf("invalid blob %s: %w", b.Name, e)
+	}
+
+	a.block.putString(b.Name)
+	a.block.putBytes(body)
+	a.block.putString(b.ContentType)
+	a.block.putString(b.ContentEncoding)
+	if nil == b.ContentLength {
+		a.block.putLong(0)
+	} else {
+		a.block.putLong(1)
+		a.block.putLong(*b.ContentLength)
+	}
+	if nil == b.LastModified {
+		a.block.putLong(0)
+	} else {
+		a.block.putLong(1)
+		a.block.putLong(b.LastModified.UnixMilli())
+	}
+	a.block.putStringMap(sortedKeys(meta), meta)
+	a.count++
+	return nil
+}
+
+// Write implements BlobSink.
+func (a *AvroWriter) Write(b *bj.Blob) error {
+	if !a.started {
+		e := a.writeHeader()
+		if nil != e {
+			return e
+		}
+	}
+
+	e := a.encode(b)
+	if nil != e {
+		return e
+	}
+
+	var interval int = a.SyncInterval
+	if interval <= 0 {
+		interval = avroSyncInterval
+	}
+	if interval <= a.block.Len() {
+		return a.Flush()
+	}
+	return nil
+}
+
+func (a *AvroWriter) compressed() ([]byte, error) {
+	if AvroDeflate != a.codec() {
+		return a.block.Bytes(), nil
+	}
+
+	var buf bytes.Buffer
+	fw, e := flate.NewWriter(&buf, flate.DefaultCompression)
+	if nil != e {
+		return nil, fmt.Errorf("could not create deflate writer: %w", e)
+	}
+	_, e = fw.Write(a.block.Bytes())
+	if nil == e {
+		e = fw.Close()
+	}
+	if nil != e {
+		return nil, fmt.Errorf("could not compress block: %w", e)
+	}
+	return buf.Bytes(), nil
+}
+
+// Flush writes the pending records as a block.
+func (a *AvroWriter) Flush() error {
+	if 0 == a.count {
+		return nil
+	}
+
+	data, e := a.compressed()
+	if nil != e {
+		return e
+	}
+
+	a.out.Reset()
+	a.out.putLong(a.count)
+	a.out.putBytes(data)
+	a.out.Write(a.sync[:])
+	_, e = a.Writer.Write(a.out.Bytes())
+	if nil != e {
+		return fmt.Errorf("could not write avro block: %w", e)
+	}
+
+	a.block.Reset()
+	a.count = 0
+	return nil
+}
+
+// Close writes the pending records, or the header if there were no records at all.
+// It does not close the underlying writer.
+func (a *AvroWriter) Close() error {
+	if !a.started {
+		return a.writeHeader()
+	}
+	return a.Flush()
+}
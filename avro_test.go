@@ -0,0 +1,181 @@
+package zip2jsons_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+	"github.com/takanoriyanagitani/go-zip2blobs2jsons"
+)
+
+// avroReader decodes the container written by AvroWriter.
+type avroReader struct {
+	t *testing.T
+	r *bytes.Reader
+}
+
+func (a avroReader) long() int64 {
+	v, err := binary.ReadVarint(a.r)
+	if err != nil {
+		a.t.Fatalf("Failed to read long: %v", err)
+	}
+	return v
+}
+
+func (a avroReader) bytes() []byte {
+	b := make([]byte, a.long())
+	_, err := io.ReadFull(a.r, b)
+	if err != nil {
+		a.t.Fatalf("Failed to read bytes: %v", err)
+	}
+	return b
+}
+
+func (a avroReader) stringMap() map[string]string {
+	m := map[string]string{}
+	for n := a.long(); n != 0; n = a.long() {
+		for range n {
+			key := string(a.bytes())
+			m[key] = string(a.bytes())
+		}
+	}
+	return m
+}
+
+type avroRecord struct {
+	name     string
+	body     []byte
+	length   *int64
+	modified *int64
+	metadata map[string]string
+}
+
+func (a avroReader) optionalLong() *int64 {
+	if a.long() == 0 {
+		return nil
+	}
+	v := a.long()
+	return &v
+}
+
+func readAvro(t *testing.T, data []byte) (map[string]string, []avroRecord) {
+	t.Helper()
+
+	if !bytes.HasPrefix(data, []byte("Obj\x01")) {
+		t.Fatalf("Missing avro magic")
+	}
+	hdr := avroReader{t: t, r: bytes.NewReader(data[4:])}
+	meta := hdr.stringMap()
+	sync := make([]byte, 16)
+	_, _ = io.ReadFull(hdr.r, sync)
+
+	var records []avroRecord
+	for hdr.r.Len() > 0 {
+		count := hdr.long()
+		block := hdr.bytes()
+		if meta["avro.codec"] == "deflate" {
+			inflated, err := io.ReadAll(flate.NewReader(bytes.NewReader(block)))
+			if err != nil {
+				t.Fatalf("Failed to inflate block: %v", err)
+			}
+			block = inflated
+		}
+		marker := make([]byte, 16)
+		_, _ = io.ReadFull(hdr.r, marker)
+		if !bytes.Equal(marker, sync) {
+			t.Fatalf("Sync marker mismatch")
+		}
+
+		rec := avroReader{t: t, r: bytes.NewReader(block)}
+		for range count {
+			var r avroRecord
+			r.name = string(rec.bytes())
+			r.body = rec.bytes()
+			_ = rec.bytes()
+			_ = rec.bytes()
+			r.length = rec.optionalLong()
+			r.modified = rec.optionalLong()
+			r.metadata = rec.stringMap()
+			records = append(records, r)
+		}
+		if rec.r.Len() != 0 {
+			t.Fatalf("Unexpected trailing block bytes: %d", rec.r.Len())
+		}
+	}
+	return meta, records
+}
+
+func TestAvroWriter(t *testing.T) {
+	t.Parallel()
+
+	for _, codec := range []zip2jsons.AvroCodec{zip2jsons.AvroNull, zip2jsons.AvroDeflate} {
+		t.Run(string(codec), func(t *testing.T) {
+			t.Parallel()
+
+			buf := new(bytes.Buffer)
+			w := &zip2jsons.AvroWriter{Writer: buf, Codec: codec, SyncInterval: 100}
+			length := int64(3)
+			modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+			for i := range 10 {
+				err := w.Write(&bj.Blob{
+					Name:          "f" + string(rune('0'+i)),
+					Body:          "YWJj",
+					Metadata:      []byte(`{"ZipName":"x.zip","entries":2}`),
+					ContentLength: &length,
+					LastModified:  &modified,
+				})
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+			err := w.Write(&bj.Blob{Name: "bare"})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			err = zip2jsons.CloseSink(w)
+			if err != nil {
+				t.Fatalf("Failed to close: %v", err)
+			}
+
+			meta, records := readAvro(t, buf.Bytes())
+			if meta["avro.schema"] != zip2jsons.AvroSchema || meta["avro.codec"] != string(codec) {
+				t.Errorf("Unexpected header metadata: %v", meta)
+			}
+			if len(records) != 11 {
+				t.Fatalf("Expected 11 records, got %d", len(records))
+			}
+			first := records[0]
+			if first.name != "f0" || string(first.body) != "abc" || *first.length != 3 {
+				t.Errorf("Unexpected record: %+v", first)
+			}
+			if *first.modified != modified.UnixMilli() {
+				t.Errorf("Unexpected modified: %d", *first.modified)
+			}
+			if first.metadata["ZipName"] != "x.zip" || first.metadata["entries"] != "2" {
+				t.Errorf("Unexpected metadata: %v", first.metadata)
+			}
+			last := records[10]
+			if last.name != "bare" || last.length != nil || last.modified != nil || len(last.metadata) != 0 {
+				t.Errorf("Unexpected record: %+v", last)
+			}
+		})
+	}
+
+	t.Run("empty container", func(t *testing.T) {
+		t.Parallel()
+
+		buf := new(bytes.Buffer)
+		err := zip2jsons.CloseSink(&zip2jsons.AvroWriter{Writer: buf})
+		if err != nil {
+			t.Fatalf("Failed to close: %v", err)
+		}
+		meta, records := readAvro(t, buf.Bytes())
+		if meta["avro.codec"] != "null" || len(records) != 0 {
+			t.Errorf("Unexpected container: %v, %d records", meta, len(records))
+		}
+	})
+}
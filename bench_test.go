@@ -0,0 +1,67 @@
+package zip2jsons_test
+
+import (
+	"io"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+	"github.com/takanoriyanagitani/go-zip2blobs2jsons"
+)
+
+const benchItems = 64
+
+// benchmarkProcessor converts an archive of benchItems items per iteration
+// and reports the allocations per item.
+func benchmarkProcessor(b *testing.B, size int, sink func(io.Writer) zip2jsons.BlobSink, workers int) {
+	b.Helper()
+
+	var pairs []string
+	for i := range benchItems {
+		pairs = append(pairs, strings.Repeat("x", i%10)+".txt", strings.Repeat("0123456789abcdef", size/16))
+	}
+	arc := newTestArchive(b, pairs...)
+	p := zip2jsons.Processor{
+		Sink:    sink(io.Discard),
+		Builder: bj.BlobBuilder{MaxBytes: int64(size), Metadata: map[string]string{"k": "v"}},
+		Workers: workers,
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	b.SetBytes(int64(size * benchItems))
+	b.ResetTimer()
+	for range b.N {
+		err := p.Process(arc)
+		if err != nil {
+			b.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	b.StopTimer()
+	runtime.ReadMemStats(&after)
+	b.ReportMetric(float64(after.Mallocs-before.Mallocs)/float64(b.N*benchItems), "allocs/item")
+	b.ReportMetric(float64(after.TotalAlloc-before.TotalAlloc)/float64(b.N*benchItems), "B/item")
+}
+
+func jsonEncoderSink(w io.Writer) zip2jsons.BlobSink { return zip2jsons.NewJsonEncoder(w, true) }
+
+func jsonStreamSink(w io.Writer) zip2jsons.BlobSink { return zip2jsons.NewJsonStreamEncoder(w, true) }
+
+func BenchmarkProcessor(b *testing.B) {
+	for _, size := range []int{1024, 64 * 1024} {
+		b.Run("json encoder/"+strconv.Itoa(size), func(b *testing.B) {
+			benchmarkProcessor(b, size, jsonEncoderSink, 1)
+		})
+		b.Run("json stream/"+strconv.Itoa(size), func(b *testing.B) {
+			benchmarkProcessor(b, size, jsonStreamSink, 1)
+		})
+		b.Run("json stream parallel/"+strconv.Itoa(size), func(b *testing.B) {
+			benchmarkProcessor(b, size, jsonStreamSink, 4)
+		})
+	}
+	b.Run("json stream/4194304", func(b *testing.B) {
+		benchmarkProcessor(b, 4<<20, jsonStreamSink, 1)
+	})
+}
@@ -0,0 +1,314 @@
+package zip2jsons_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+	"github.com/takanoriyanagitani/go-zip2blobs2jsons"
+)
+
+// cborDecoder decodes the subset of CBOR written by CborEncoder.
+type cborDecoder struct {
+	t   *testing.T
+	buf []byte
+}
+
+func (d *cborDecoder) next(n int) []byte {
+	if len(d.buf) < n {
+		d.t.Fatalf("Unexpected end of CBOR data")
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *cborDecoder) head() (byte, uint64) {
+	b := d.next(1)[0]
+	major, info := b>>5, b&0x1f
+	switch {
+	case info < 24:
+		return major, uint64(info)
+	case info == 24:
+		return major, uint64(d.next(1)[0])
+	case info == 25:
+		return major, uint64(binary.BigEndian.Uint16(d.next(2)))
+	case info == 26:
+		return major, uint64(binary.BigEndian.Uint32(d.next(4)))
+	case info == 27:
+		return major, binary.BigEndian.Uint64(d.next(8))
+	}
+	d.t.Fatalf("Unsupported CBOR info %d", info)
+	return 0, 0
+}
+
+func (d *cborDecoder) value() any {
+	first := d.buf[0]
+	major, n := d.head()
+	switch major {
+	case 0:
+		return int64(n)
+	case 1:
+		return -1 - int64(n)
+	case 2:
+		return bytes.Clone(d.next(int(n)))
+	case 3:
+		return string(d.next(int(n)))
+	case 4:
+		arr := make([]any, n)
+		for i := range arr {
+			arr[i] = d.value()
+		}
+		return arr
+	case 5:
+		m := map[string]any{}
+		for range n {
+			key, _ := d.value().(string)
+			m[key] = d.value()
+		}
+		return m
+	case 6:
+		s, _ := d.value().(string)
+		parsed, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil || n != 0 {
+			d.t.Fatalf("Invalid date/time tag %d: %v", n, err)
+		}
+		return parsed
+	}
+	switch first {
+	case 0xf4:
+		return false
+	case 0xf5:
+		return true
+	case 0xf6:
+		return nil
+	case 0xfb:
+		return math.Float64frombits(n)
+	}
+	d.t.Fatalf("Unsupported CBOR byte %x", first)
+	return nil
+}
+
+// msgpackDecoder decodes the subset of MessagePack written by MsgpackEncoder.
+type msgpackDecoder struct{ cborDecoder }
+
+func (d *msgpackDecoder) size(n int) int {
+	switch n {
+	case 1:
+		return int(d.next(1)[0])
+	case 2:
+		return int(binary.BigEndian.Uint16(d.next(2)))
+	default:
+		return int(binary.BigEndian.Uint32(d.next(4)))
+	}
+}
+
+func (d *msgpackDecoder) mapOf(n int) map[string]any {
+	m := map[string]any{}
+	for range n {
+		key, _ := d.value().(string)
+		m[key] = d.value()
+	}
+	return m
+}
+
+func (d *msgpackDecoder) value() any {
+	b := d.next(1)[0]
+	switch {
+	case b <= 0x7f:
+		return int64(b)
+	case b >= 0xe0:
+		return int64(int8(b))
+	case b&0xe0 == 0xa0:
+		return string(d.next(int(b & 0x1f)))
+	case b&0xf0 == 0x80:
+		return d.mapOf(int(b & 0x0f))
+	}
+	switch b {
+	case 0xc0:
+		return nil
+	case 0xc2:
+		return false
+	case 0xc3:
+		return true
+	case 0xc4, 0xc5, 0xc6:
+		return bytes.Clone(d.next(d.size(1 << (b - 0xc4))))
+	case 0xcb:
+		return math.Float64frombits(binary.BigEndian.Uint64(d.next(8)))
+	case 0xcc:
+		return int64(d.next(1)[0])
+	case 0xcd:
+		return int64(binary.BigEndian.Uint16(d.next(2)))
+	case 0xce:
+		return int64(binary.BigEndian.Uint32(d.next(4)))
+	case 0xd6:
+		d.next(1)
+		return time.Unix(int64(binary.BigEndian.Uint32(d.next(4))), 0)
+	case 0xd7:
+		d.next(1)
+		v := binary.BigEndian.Uint64(d.next(8))
+		return time.Unix(int64(v&(1<<34-1)), int64(v>>34))
+	case 0xd9, 0xda, 0xdb:
+		return string(d.next(d.size(1 << (b - 0xd9))))
+	case 0xde:
+		return d.mapOf(d.size(2))
+	}
+	d.t.Fatalf("Unsupported MessagePack byte %x", b)
+	return nil
+}
+
+func binaryBlobs() []*bj.Blob {
+	length := int64(5)
+	modified := time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC)
+	return []*bj.Blob{
+		{
+			Name:          "a.bin",
+			ContentType:   "application/octet-stream",
+			Body:          "AAH+/wA=",
+			Metadata:      []byte(`{"ZipName":"x.zip","n":-3,"f":1.5,"big":70000,"ok":true,"none":null}`),
+			ContentLength: &length,
+			LastModified:  &modified,
+		},
+		{Name: "b.txt", Body: ""},
+	}
+}
+
+func checkBinaryRecord(t *testing.T, rec map[string]any) {
+	t.Helper()
+
+	if rec["name"] != "a.bin" || rec["content_transfer_encoding"] != "binary" {
+		t.Errorf("Unexpected record: %v", rec)
+	}
+	if body, _ := rec["body"].([]byte); !bytes.Equal(body, []byte{0, 1, 0xfe, 0xff, 0}) {
+		t.Errorf("Unexpected body: %v", rec["body"])
+	}
+	if rec["content_length"] != int64(5) {
+		t.Errorf("Unexpected content length: %v", rec["content_length"])
+	}
+	if modified, _ := rec["last_modified"].(time.Time); !modified.Equal(time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC)) {
+		t.Errorf("Unexpected last modified: %v", rec["last_modified"])
+	}
+	meta, _ := rec["metadata"].(map[string]any)
+	if meta["ZipName"] != "x.zip" || meta["n"] != int64(-3) || meta["f"] != 1.5 ||
+		meta["big"] != int64(70000) || meta["ok"] != true || meta["none"] != nil {
+		t.Errorf("Unexpected metadata: %v", meta)
+	}
+}
+
+func TestCborEncoder(t *testing.T) {
+	t.Parallel()
+
+	buf := new(bytes.Buffer)
+	enc := &zip2jsons.CborEncoder{Writer: buf}
+	for _, b := range binaryBlobs() {
+		err := enc.Write(b)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	dec := &cborDecoder{t: t, buf: buf.Bytes()}
+	first, _ := dec.value().(map[string]any)
+	checkBinaryRecord(t, first)
+	second, _ := dec.value().(map[string]any)
+	if second["name"] != "b.txt" || len(second) != 5 {
+		t.Errorf("Unexpected second record: %v", second)
+	}
+	if len(dec.buf) != 0 {
+		t.Errorf("Unexpected trailing bytes: %d", len(dec.buf))
+	}
+}
+
+func TestMsgpackEncoder(t *testing.T) {
+	t.Parallel()
+
+	buf := new(bytes.Buffer)
+	enc := &zip2jsons.MsgpackEncoder{Writer: buf}
+	for _, b := range binaryBlobs() {
+		err := enc.Write(b)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	dec := &msgpackDecoder{cborDecoder{t: t, buf: buf.Bytes()}}
+	first, _ := dec.value().(map[string]any)
+	checkBinaryRecord(t, first)
+	second, _ := dec.value().(map[string]any)
+	if second["name"] != "b.txt" || len(second) != 5 {
+		t.Errorf("Unexpected second record: %v", second)
+	}
+	if len(dec.buf) != 0 {
+		t.Errorf("Unexpected trailing bytes: %d", len(dec.buf))
+	}
+}
+
+func TestBinaryEncoder_InvalidBody(t *testing.T) {
+	t.Parallel()
+
+	enc := &zip2jsons.CborEncoder{Writer: new(bytes.Buffer)}
+	err := enc.Write(&bj.Blob{Name: "x", Body: "!!!"})
+	if err == nil {
+		t.Errorf("Expected an error for an invalid base64 body")
+	}
+}
+
+func TestBinaryEncoder_WriteRaw(t *testing.T) {
+	t.Parallel()
+
+	content := "\x00raw\xffbytes"
+	tests := []struct {
+		name   string
+		sink   func(buf *bytes.Buffer) zip2jsons.RawBlobSink
+		decode func(buf []byte) map[string]any
+	}{
+		{
+			"cbor",
+			func(buf *bytes.Buffer) zip2jsons.RawBlobSink { return &zip2jsons.CborEncoder{Writer: buf} },
+			func(buf []byte) map[string]any {
+				dec := &cborDecoder{t: t, buf: buf}
+				dec.value() // large.bin
+				rec, _ := dec.value().(map[string]any)
+				return rec
+			},
+		},
+		{
+			"msgpack",
+			func(buf *bytes.Buffer) zip2jsons.RawBlobSink { return &zip2jsons.MsgpackEncoder{Writer: buf} },
+			func(buf []byte) map[string]any {
+				dec := &msgpackDecoder{cborDecoder{t: t, buf: buf}}
+				dec.value() // large.bin
+				rec, _ := dec.value().(map[string]any)
+				return rec
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := new(bytes.Buffer)
+			f := zip2jsons.ByteReader{Reader: bytes.NewReader(newZipBytes(t, 0, "a.bin", content))}.AsFileLike()
+			arc, err := f.ToZip()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			p := zip2jsons.Processor{Sink: tt.sink(buf), Builder: bj.BlobBuilder{MaxBytes: 1024}}
+			err = p.Process(arc)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			rec := tt.decode(buf.Bytes())
+			if body, _ := rec["body"].([]byte); string(body) != content {
+				t.Errorf("Expected body %q, got %v", content, rec["body"])
+			}
+			if rec["name"] != "a.bin" || rec["content_length"] != int64(len(content)) {
+				t.Errorf("Unexpected record: %v", rec)
+			}
+		})
+	}
+}
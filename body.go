@@ -0,0 +1,172 @@
+package zip2jsons
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+)
+
+// bodyPool holds the scratch buffers of the item contents written to a RawBlobSink.
+var bodyPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+func getBody() *bytes.Buffer {
+	var buf *bytes.Buffer = bodyPool.Get().(*bytes.Buffer) //nolint:forcetypeassert // only buffers are pooled
+	buf.Reset()
+	return buf
+}
+
+// maxPooledBody is the capacity above which the buffers are dropped rather than pooled,
+// so that a few large items do not keep large buffers for the life of the process.
+const maxPooledBody = 64 << 10
+
+func putBody(buf *bytes.Buffer) {
+	if maxPooledBody < buf.Cap() {
+		return
+	}
+	bodyPool.Put(buf)
+}
+
+// readBody reads at most maxBytes of the item content into the buffer.
+// The buffer is grown once from the declared size, which is capped by maxBytes.
+func (i ZipItem) readBody(ctx context.Context, maxBytes int64, buf *bytes.Buffer) error {
+	rc, e := i.File.Open()
+	if nil != e {
+		return fmt.Errorf("could not open zip file %s: %w", i.Name(), e)
+	}
+	defer rc.Close() //nolint:errcheck// the "file" is read only
+
+	var size uint64 = min(i.UncompressedSize64, uint64(maxBytes)) //nolint:gosec // not negative
+	buf.Grow(int(size))                                           //nolint:gosec // capped by maxBytes
+
+	_, e = buf.ReadFrom(io.LimitReader(ContextReader{Reader: rc, Context: ctx}, maxBytes))
+	if nil != ctx.Err() {
+		return canceled(ctx)
+	}
+	return e
+}
+
+// headerBlob creates the blob of the item without its body,
+// with the same fields as bj.BlobBuilder.NewBlobFromReader.
+func headerBlob(builder bj.BlobBuilder, name string, length int64) (*bj.Blob, error) {
+	var blob bj.Blob = bj.Blob{
+		Name:                    name,
+		ContentType:             builder.ContentType,
+		ContentEncoding:         builder.ContentEncoding,
+		ContentTransferEncoding: "base64",
+		ContentLength:           &length,
+		LastModified:            builder.LastModified,
+	}
+	if 0 < len(builder.Metadata) {
+		meta, e := json.Marshal(builder.Metadata)
+		if nil != e {
+			return nil, e
+		}
+		blob.Metadata = meta
+	}
+	return &blob, nil
+}
+
+// toRaw reads the content of the item into a pooled buffer
+// and returns it with the blob of the item without its body.
+// The buffer must be returned with putBody once written.
+func (i ZipItem) toRaw(ctx context.Context, builder bj.BlobBuilder) (*bj.Blob, *bytes.Buffer, error) {
+	if nil != ctx.Err() {
+		return nil, nil, canceled(ctx)
+	}
+
+	var buf *bytes.Buffer = getBody()
+	// if builder.MaxBytes is unset, the body is empty as with ToBlob
+	e := i.readBody(ctx, max(builder.MaxBytes, 0), buf)
+	if nil != e {
+		putBody(buf)
+		return nil, nil, e
+	}
+
+	var modified time.Time = i.Modified()
+	builder.LastModified = &modified
+	blob, e := headerBlob(builder, i.Name(), int64(buf.Len()))
+	if nil != e {
+		putBody(buf)
+		return nil, nil, e
+	}
+	return blob, buf, nil
+}
+
+// readItem converts the item while reading its content from a stream, such as a tar or a 7z folder.
+// The size is the declared size of the content. A zero modification time is left out of the blob.
+func (p Processor) readItem(
+	ctx context.Context,
+	info ItemInfo,
+	modified time.Time,
+	size int64,
+	content io.Reader,
+	checks map[string]string,
+	entry func() Entry,
+) error {
+	if 0 < p.ItemTimeout {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.ItemTimeout)
+		defer cancel()
+	}
+
+	var bldr bj.BlobBuilder = p.itemBuilder(info, checks, size)
+	if !modified.IsZero() {
+		bldr.LastModified = &modified
+	}
+
+	if ss, ok := p.streamSink(); ok {
+		blb, e := headerBlob(bldr, info.Name, 0)
+		if nil != e {
+			p.stats.failed++
+			return fmt.Errorf("could not convert item to blob: %w", e)
+		}
+		return p.streamContent(ctx, blb, content, ss)
+	}
+
+	item, e := p.convertReader(ctx, bldr, info.Name, size, content)
+	if nil != e {
+		p.stats.failed++
+		return fmt.Errorf("could not convert item to blob: %w", e)
+	}
+	return p.write(item, entry)
+}
+
+// convertReader reads at most Builder.MaxBytes of the content as with ToBlob.
+func (p Processor) convertReader(
+	ctx context.Context, bldr bj.BlobBuilder, name string, size int64, content io.Reader,
+) (converted, error) {
+	var maxBytes int64 = max(bldr.MaxBytes, 0)
+	var rdr io.Reader = ContextReader{Reader: content, Context: ctx}
+	if _, ok := p.rawSink(); !ok {
+		blb, e := bldr.NewBlobFromReader(rdr, name)
+		if nil != ctx.Err() {
+			return converted{}, canceled(ctx)
+		}
+		return converted{blob: blb}, e
+	}
+
+	var buf *bytes.Buffer = getBody()
+	buf.Grow(int(min(max(size, 0), maxBytes)))
+	_, e := buf.ReadFrom(io.LimitReader(rdr, maxBytes))
+	if nil != ctx.Err() {
+		e = canceled(ctx)
+	}
+	if nil != e {
+		putBody(buf)
+		return converted{}, e
+	}
+	blb, e := headerBlob(bldr, name, int64(buf.Len()))
+	if nil != e {
+		putBody(buf)
+		return converted{}, e
+	}
+	return converted{blob: blb, content: buf}, nil
+}
@@ -0,0 +1,170 @@
+package zip2jsons
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+)
+
+// Content types of the records bracketing the items of an archive.
+const (
+	ContentTypeHeader  = "application/x-zip2jsons-header"
+	ContentTypeTrailer = "application/x-zip2jsons-trailer"
+
+	// ContentTypeError is the content type of the records written instead of the items which could not be converted.
+	ContentTypeError = "application/x-zip2jsons-error"
+)
+
+// Trailer statuses.
+const (
+	StatusComplete = "complete"
+	StatusAborted  = "aborted"
+)
+
+// ArchiveHeader is the metadata of the record written before the items of an archive.
+// Entries and Size are -1 for streamed archives, such as tar, where they are not known in advance.
+type ArchiveHeader struct {
+	Record  string `json:"record"`
+	ZipName string `json:"zip_name"`
+	Comment string `json:"comment"`
+	Entries int    `json:"entries"`
+	Size    int64  `json:"size"`
+
+	// Format is the input format other than zip, such as tar+gzip.
+	Format string `json:"format,omitempty"`
+
+	// Prefix is the length of the data before the zip (see ZipArchive.Prefix).
+	Prefix int64 `json:"prefix,omitempty"`
+}
+
+// ArchiveTrailer is the metadata of the record written after the items of an archive.
+type ArchiveTrailer struct {
+	Record    string `json:"record"`
+	ZipName   string `json:"zip_name"`
+	Status    string `json:"status"`
+	Items     int    `json:"items"`
+	Skipped   int    `json:"skipped"`
+	Failed    int    `json:"failed"`
+	Bytes     int64  `json:"bytes"`
+	ElapsedMs int64  `json:"elapsed_ms"`
+}
+
+// ItemError is the metadata of the record written instead of an item which could not be converted,
+// such as a 7z item compressed with an unsupported coder. The record is counted as failed in the trailer.
+type ItemError struct {
+	Record  string `json:"record"`
+	ZipName string `json:"zip_name"`
+	Name    string `json:"name"`
+	Error   string `json:"error"`
+}
+
+// archiveStats counts the items of an archive for its trailer.
+type archiveStats struct {
+	items   int
+	skipped int
+	failed  int
+	bytes   int64
+	started time.Time
+}
+
+func (s *archiveStats) written(b *bj.Blob) {
+	s.items++
+	if nil != b.ContentLength {
+		s.bytes += *b.ContentLength
+	}
+}
+
+func recordBlob(name, contentType string, meta any) (*bj.Blob, error) {
+	raw, e := json.Marshal(meta)
+	if nil != e {
+		return nil, fmt.Errorf("could not encode record metadata: %w", e)
+	}
+	var length int64
+	return &bj.Blob{
+		Name:                    name,
+		ContentType:             contentType,
+		ContentTransferEncoding: "base64",
+		Metadata:                raw,
+		ContentLength:           &length,
+	}, nil
+}
+
+// ToBlob converts the header into a record with an empty body.
+func (h ArchiveHeader) ToBlob() (*bj.Blob, error) {
+	h.Record = "header"
+	return recordBlob(h.ZipName, ContentTypeHeader, h)
+}
+
+// ToBlob converts the error into a record named after the item, with an empty body.
+func (i ItemError) ToBlob() (*bj.Blob, error) {
+	i.Record = "error"
+	return recordBlob(i.Name, ContentTypeError, i)
+}
+
+// ToBlob converts the trailer into a record with an empty body.
+func (t ArchiveTrailer) ToBlob() (*bj.Blob, error) {
+	t.Record = "trailer"
+	return recordBlob(t.ZipName, ContentTypeTrailer, t)
+}
+
+func (p Processor) zipName() string { return p.Builder.Metadata[MetaZipName] }
+
+// header returns the header record of the archive.
+func (a ZipArchive) header() ArchiveHeader {
+	return ArchiveHeader{
+		Comment: a.Comment,
+		Entries: len(a.Files()),
+		Size:    a.Size,
+		Prefix:  a.Prefix,
+	}
+}
+
+func (p Processor) writeHeader(hdr ArchiveHeader) error {
+	hdr.ZipName = p.zipName()
+	blb, e := hdr.ToBlob()
+	if nil != e {
+		return e
+	}
+	e = p.Sink.Write(blb)
+	if nil != e {
+		return fmt.Errorf("could not write header: %w", e)
+	}
+	return nil
+}
+
+func (p Processor) writeTrailer(status string) error {
+	var trl ArchiveTrailer = ArchiveTrailer{
+		ZipName:   p.zipName(),
+		Status:    status,
+		Items:     p.stats.items,
+		Skipped:   p.stats.skipped,
+		Failed:    p.stats.failed,
+		Bytes:     p.stats.bytes,
+		ElapsedMs: time.Since(p.stats.started).Milliseconds(),
+	}
+	blb, e := trl.ToBlob()
+	if nil != e {
+		return e
+	}
+	e = p.Sink.Write(blb)
+	if nil != e {
+		return fmt.Errorf("could not write trailer: %w", e)
+	}
+	return nil
+}
+
+func (p Processor) writeItemError(name string, err error) error {
+	p.stats.failed++
+	var rec ItemError = ItemError{ZipName: p.zipName(), Name: name, Error: err.Error()}
+	blb, e := rec.ToBlob()
+	if nil != e {
+		return e
+	}
+	e = p.Sink.Write(blb)
+	if nil != e {
+		return fmt.Errorf("could not write error record: %w", e)
+	}
+	return nil
+}
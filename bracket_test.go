@@ -0,0 +1,88 @@
+package zip2jsons_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+	"github.com/takanoriyanagitani/go-zip2blobs2jsons"
+)
+
+func TestProcessor_Bracket(t *testing.T) {
+	t.Parallel()
+
+	var bldr bj.BlobBuilder = bj.BlobBuilder{
+		MaxBytes: 1024,
+		Metadata: map[string]string{zip2jsons.MetaZipName: "x.zip"},
+	}
+
+	t.Run("complete", func(t *testing.T) {
+		t.Parallel()
+
+		var sink zip2jsons.SliceSink
+		var proc zip2jsons.Processor = zip2jsons.Processor{Sink: &sink, Builder: bldr, Bracket: true}
+		arc := newTestArchive(t, "a.txt", "12345", "b.txt", "678")
+		arc.Size = 321
+		err := proc.Process(arc)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(sink.Blobs) != 4 {
+			t.Fatalf("Expected 4 records, got %d", len(sink.Blobs))
+		}
+
+		var hdr zip2jsons.ArchiveHeader
+		err = json.Unmarshal(sink.Blobs[0].Metadata, &hdr)
+		if err != nil {
+			t.Fatalf("Failed to decode header: %v", err)
+		}
+		if sink.Blobs[0].ContentType != zip2jsons.ContentTypeHeader {
+			t.Errorf("Unexpected header content type: %s", sink.Blobs[0].ContentType)
+		}
+		if hdr.Record != "header" || hdr.ZipName != "x.zip" || hdr.Entries != 2 || hdr.Size != 321 {
+			t.Errorf("Unexpected header: %+v", hdr)
+		}
+
+		var trl zip2jsons.ArchiveTrailer
+		err = json.Unmarshal(sink.Blobs[3].Metadata, &trl)
+		if err != nil {
+			t.Fatalf("Failed to decode trailer: %v", err)
+		}
+		if sink.Blobs[3].ContentType != zip2jsons.ContentTypeTrailer {
+			t.Errorf("Unexpected trailer content type: %s", sink.Blobs[3].ContentType)
+		}
+		if trl.Status != zip2jsons.StatusComplete || trl.Items != 2 || trl.Bytes != 8 || trl.Failed != 0 {
+			t.Errorf("Unexpected trailer: %+v", trl)
+		}
+	})
+
+	t.Run("aborted", func(t *testing.T) {
+		t.Parallel()
+
+		expectedErr := io.ErrShortWrite
+		var blobs []*bj.Blob
+		var sink zip2jsons.BlobSinkFn = func(b *bj.Blob) error {
+			blobs = append(blobs, b)
+			if 2 == len(blobs) {
+				return expectedErr
+			}
+			return nil
+		}
+		var proc zip2jsons.Processor = zip2jsons.Processor{Sink: sink, Builder: bldr, Bracket: true}
+		err := proc.Process(newTestArchive(t, "a.txt", "1", "b.txt", "2"))
+		if !errors.Is(err, expectedErr) {
+			t.Fatalf("Expected error %v, got %v", expectedErr, err)
+		}
+
+		var trl zip2jsons.ArchiveTrailer
+		err = json.Unmarshal(blobs[len(blobs)-1].Metadata, &trl)
+		if err != nil {
+			t.Fatalf("Failed to decode trailer: %v", err)
+		}
+		if trl.Status != zip2jsons.StatusAborted || trl.Items != 0 {
+			t.Errorf("Unexpected trailer: %+v", trl)
+		}
+	})
+}
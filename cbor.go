@@ -0,0 +1,127 @@
+package zip2jsons
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+)
+
+// CBOR major types.
+const (
+	cborUint   = 0
+	cborNegInt = 1
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborTag    = 6
+	cborSimple = 7
+)
+
+// cborTagDateTime is the tag of an RFC 3339 date/time string.
+const cborTagDateTime = 0
+
+type cborWriter struct{ buf bytes.Buffer }
+
+func (c *cborWriter) head(major byte, n uint64) {
+	var m byte = major << 5
+	switch {
+	case n < 24:
+		c.buf.WriteByte(m | byte(n))
+	case n <= math.MaxUint8:
+		c.buf.Write([]byte{m | 24, byte(n)})
+	case n <= math.MaxUint16:
+		c.buf.WriteByte(m | 25)
+		c.buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	case n <= math.MaxUint32:
+		c.buf.WriteByte(m | 26)
+		c.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	default:
+		c.buf.WriteByte(m | 27)
+		c.buf.Write(binary.BigEndian.AppendUint64(nil, n))
+	}
+}
+
+func (c *cborWriter) null() { c.buf.WriteByte(cborSimple<<5 | 22) }
+
+func (c *cborWriter) boolean(b bool) {
+	if b {
+		c.buf.WriteByte(cborSimple<<5 | 21)
+		return
+	}
+	c.buf.WriteByte(cborSimple<<5 | 20)
+}
+
+func (c *cborWriter) integer(i int64) {
+	if i < 0 {
+		c.head(cborNegInt, uint64(-1-i))
+		return
+	}
+	c.head(cborUint, uint64(i))
+}
+
+func (c *cborWriter) float(f float64) {
+	c.buf.WriteByte(cborSimple<<5 | 27)
+	c.buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(f)))
+}
+
+func (c *cborWriter) text(s string) {
+	c.head(cborText, uint64(len(s)))
+	c.buf.WriteString(s)
+}
+
+func (c *cborWriter) binary(b []byte) {
+	c.head(cborBytes, uint64(len(b)))
+	c.buf.Write(b)
+}
+
+func (c *cborWriter) timestamp(t time.Time) {
+	c.head(cborTag, cborTagDateTime)
+	c.text(t.Format(time.RFC3339Nano))
+}
+
+func (c *cborWriter) arrayHeader(n int) { c.head(cborArray, uint64(n)) }
+
+func (c *cborWriter) mapHeader(n int) { c.head(cborMap, uint64(n)) }
+
+// CborEncoder writes the blobs as a CBOR sequence (RFC 8742) of maps
+// using the keys of the JSON schema, with the body as a byte string.
+type CborEncoder struct {
+	io.Writer
+
+	cw cborWriter
+}
+
+// Write implements BlobSink.
+func (c *CborEncoder) Write(b *bj.Blob) error {
+	body, e := blobBody(b)
+	if nil != e {
+		return e
+	}
+	return c.WriteRaw(b, body)
+}
+
+// WriteRaw implements RawBlobSink.
+func (c *CborEncoder) WriteRaw(b *bj.Blob, content []byte) error {
+	fields, e := blobFields(b, content)
+	if nil != e {
+		return e
+	}
+
+	c.cw.buf.Reset()
+	e = writeFields(&c.cw, fields)
+	if nil != e {
+		return fmt.Errorf("could not encode blob %s: %w", b.Name, e)
+	}
+
+	_, e = c.Writer.Write(c.cw.buf.Bytes())
+	if nil != e {
+		return fmt.Errorf("could not write blob: %w", e)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+	zj "github.com/takanoriyanagitani/go-zip2blobs2jsons"
+)
+
+func extractFile(x *zj.Extractor, path string, zipSizeMax int64) error {
+	f, closer, e := openArchive(path)
+	if nil != e {
+		return e
+	}
+	defer closer.Close() //nolint:errcheck // the archive is read only
+
+	arc, e := f.ToZipLimited(zipSizeMax)
+	if nil != e {
+		return fmt.Errorf("could not read %s: %w", path, e)
+	}
+	return x.Extract(arc)
+}
+
+// extractNdjson extracts the blobs written by the convert mode.
+func extractNdjson(x *zj.Extractor, r io.Reader) error {
+	var dec *json.Decoder = json.NewDecoder(r)
+	for {
+		var blob bj.Blob
+		e := dec.Decode(&blob)
+		if errors.Is(e, io.EOF) {
+			return nil
+		}
+		if nil != e {
+			return fmt.Errorf("could not decode blob: %w", e)
+		}
+		e = x.Write(&blob)
+		if nil != e {
+			return e
+		}
+	}
+}
+
+// runExtract writes the items of the archives to a directory.
+func runExtract(args []string) error {
+	var fs *flag.FlagSet = flag.NewFlagSet("extract", flag.ExitOnError)
+	var dir string
+	var exist string
+	var zipSizeMax int64
+	var itemSizeMax int64
+	var totalSizeMax int64
+	var ratioMax float64
+	var ndjson bool
+	var stdinList bool
+	var nulList bool
+	var strict bool
+	var spill spillFlags
+
+	fs.StringVar(&dir, "dir", ".", "target directory")
+	fs.StringVar(&exist, "exist", string(zj.ExistFail), "existing files: fail, overwrite or skip")
+	fs.Int64Var(&zipSizeMax, "zip-size-max", 10485760, "zip file size limit")
+	fs.Int64Var(&itemSizeMax, "item-size-max", 1048576, "zip item size limit")
+	fs.Int64Var(&totalSizeMax, "total-size-max", 0, "limit of the bytes written (0: no limit)")
+	fs.Float64Var(&ratioMax, "ratio-max", 0, "limit of the item compression ratio (0: no limit)")
+	fs.BoolVar(&ndjson, "ndjson", false, "read NDJSON blobs from stdin instead of a zip file")
+	fs.BoolVar(&stdinList, "stdin-list", false, "read the zip paths from stdin instead of a zip file")
+	fs.BoolVar(&nulList, "null", false, "the stdin paths are separated by NUL instead of newline")
+	fs.BoolVar(&strict, "strict", false, "abort on the first failed archive")
+	spill.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s extract [flags] [zip file, directory or URL ...]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	e := fs.Parse(args)
+	if nil != e {
+		return e
+	}
+
+	policy, e := zj.ParseExistPolicy(exist)
+	if nil != e {
+		return e
+	}
+	e = os.MkdirAll(dir, 0o755)
+	if nil != e {
+		return e
+	}
+	var x zj.Extractor = zj.Extractor{
+		Dir:      dir,
+		Policy:   policy,
+		MaxBytes: itemSizeMax,
+		MaxTotal: totalSizeMax,
+		MaxRatio: ratioMax,
+	}
+
+	var paths []string
+	switch {
+	case ndjson:
+		return extractNdjson(&x, bufio.NewReader(os.Stdin))
+	case stdinList:
+		paths, e = readPathList(os.Stdin, nulList)
+	case 0 < fs.NArg():
+		paths, e = expandPaths(fs.Args(), zipExts, strict)
+	default:
+		arc, closer, err := spill.stdinArchive(zipSizeMax)
+		if nil != err {
+			return err
+		}
+		defer closer.Close() //nolint:errcheck // only the spill file is removed
+		e = x.Extract(arc)
+	}
+	if nil != e {
+		return e
+	}
+
+	var failed int
+	for _, path := range paths {
+		e = extractFile(&x, path, zipSizeMax)
+		if nil == e {
+			continue
+		}
+		if strict {
+			return e
+		}
+		failed++
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, e)
+	}
+
+	if 0 < failed {
+		return fmt.Errorf("%d of %d archives failed", failed, len(paths))
+	}
+	return nil
+}
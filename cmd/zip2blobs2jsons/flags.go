@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"strings"
+
+	zj "github.com/takanoriyanagitani/go-zip2blobs2jsons"
+)
+
+var errInvalidMeta = errors.New("metadata must be given as key=value")
+
+// metaFlag collects the repeatable -meta key=value flags.
+type metaFlag map[string]string
+
+func (m metaFlag) String() string {
+	var pairs []string
+	for key, val := range m {
+		pairs = append(pairs, key+"="+val)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (m metaFlag) Set(s string) error {
+	key, val, ok := strings.Cut(s, "=")
+	if !ok || "" == key {
+		return errInvalidMeta
+	}
+	m[key] = val
+	return nil
+}
+
+// spillFlags configures the spilling of large stdin archives to a temporary file.
+type spillFlags struct {
+	threshold int64
+	dir       string
+}
+
+func (s *spillFlags) register(fs *flag.FlagSet) {
+	fs.Int64Var(&s.threshold, "spill-threshold", 67108864, "stdin archives larger than this are spilled to a temporary file (0: keep in memory)")
+	fs.StringVar(&s.dir, "spill-dir", "", "directory of the spill file (default: the temporary directory)")
+}
+
+func (s spillFlags) reader(r io.Reader) zj.Reader {
+	return zj.Reader{Reader: r, SpillThreshold: s.threshold, SpillDir: s.dir}
+}
+
+// stdinArchive reads the archive from stdin. The Closer removes the spill file.
+func (s spillFlags) stdinArchive(zipSizeMax int64) (zj.ZipArchive, io.Closer, error) {
+	f, closer, e := s.reader(os.Stdin).ToFileLike(zipSizeMax)
+	if nil != e {
+		return zj.ZipArchive{}, nil, e
+	}
+	arc, e := f.ToZip()
+	if nil != e {
+		_ = closer.Close()
+		return zj.ZipArchive{}, nil, e
+	}
+	return arc, closer, nil
+}
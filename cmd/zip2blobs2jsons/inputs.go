@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	zj "github.com/takanoriyanagitani/go-zip2blobs2jsons"
+)
+
+// Extensions of the archives found in the directories.
+var (
+	zipExts     = []string{".zip"}
+	archiveExts = []string{
+		".zip", ".7z",
+		".tar", ".tgz", ".tar.gz", ".tbz2", ".tar.bz2", ".txz", ".tar.xz", ".tzst", ".tar.zst",
+	}
+)
+
+// hasExt reports whether the path has one of the extensions, ignoring the case.
+func hasExt(path string, exts []string) bool {
+	var lower string = strings.ToLower(path)
+	for _, ext := range exts {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitSegments returns the segments of a split zip (name.z01, ..., name.zip),
+// or nil for any other file. Only the .zip (or .ZIP) files are looked for segments.
+func splitSegments(path string) ([]string, error) {
+	if ext := filepath.Ext(path); ".zip" != ext && ".ZIP" != ext {
+		return nil, nil
+	}
+	segments, e := zj.SplitZipSegments(path)
+	if nil != e || len(segments) < 2 {
+		return nil, e
+	}
+	return segments, nil
+}
+
+// walkArchives returns the files with the extensions under the root directory in lexical order.
+func walkArchives(root string, exts []string) ([]string, error) {
+	var paths []string
+	e := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if nil != err {
+			return err
+		}
+		if d.Type().IsRegular() && hasExt(path, exts) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	return paths, e
+}
+
+// isURL reports whether the path is a http(s) URL.
+func isURL(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// expandPaths replaces the directories with the archives with the extensions under them.
+// URLs are kept as they are.
+// Unless strict, the paths which cannot be found are kept too, to be reported as failed archives.
+func expandPaths(args []string, exts []string, strict bool) ([]string, error) {
+	var paths []string
+	for _, arg := range args {
+		if isURL(arg) {
+			paths = append(paths, arg)
+			continue
+		}
+		st, e := os.Stat(arg)
+		if nil != e && strict {
+			return nil, e
+		}
+		if nil != e || !st.IsDir() {
+			paths = append(paths, arg)
+			continue
+		}
+		found, e := walkArchives(arg, exts)
+		if nil != e {
+			return nil, e
+		}
+		paths = append(paths, found...)
+	}
+	return paths, nil
+}
+
+// readPathList reads the paths separated by newlines or NUL bytes.
+func readPathList(r io.Reader, nul bool) ([]string, error) {
+	var sep byte = '\n'
+	if nul {
+		sep = 0
+	}
+
+	var s *bufio.Scanner = bufio.NewScanner(r)
+	s.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		i := bytes.IndexByte(data, sep)
+		if 0 <= i {
+			return i + 1, data[:i], nil
+		}
+		if atEOF && 0 < len(data) {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	})
+
+	var paths []string
+	for s.Scan() {
+		var path string = s.Text()
+		if !nul {
+			path = strings.TrimSuffix(path, "\r")
+		}
+		if "" != path {
+			paths = append(paths, path)
+		}
+	}
+	return paths, s.Err()
+}
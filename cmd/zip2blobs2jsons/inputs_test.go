@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitSegments(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for _, name := range []string{"x.zip", "x.z01", "x.7z", "x.tar.gz", "x.tar.z01", "y.ZIP", "y.Z01"} {
+		err := os.WriteFile(filepath.Join(dir, name), nil, 0o600)
+		if err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		expected int
+	}{
+		{"x.zip", 2},
+		{"y.ZIP", 2},
+		{"x.7z", 0},
+		{"x.tar.gz", 0},
+	}
+	for _, tt := range tests {
+		segments, err := splitSegments(filepath.Join(dir, tt.name))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(segments) != tt.expected {
+			t.Errorf("%s: expected %d segments, got %v", tt.name, tt.expected, segments)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	zj "github.com/takanoriyanagitani/go-zip2blobs2jsons"
+)
+
+func listFile(lister *zj.EntryLister, path string, zipSizeMax int64) error {
+	f, closer, e := openArchive(path)
+	if nil != e {
+		return e
+	}
+	defer closer.Close() //nolint:errcheck // the archive is read only
+
+	arc, e := f.ToZipLimited(zipSizeMax)
+	if nil != e {
+		return fmt.Errorf("could not read %s: %w", path, e)
+	}
+	return lister.List(path, arc)
+}
+
+// runList writes the central directory entries of the archives without reading the item data.
+func runList(args []string) error {
+	var fs *flag.FlagSet = flag.NewFlagSet("list", flag.ExitOnError)
+	var zipSizeMax int64
+	var zipName string
+	var format string
+	var stdinList bool
+	var nulList bool
+	var strict bool
+	var spill spillFlags
+
+	fs.Int64Var(&zipSizeMax, "zip-size-max", 10485760, "zip file size limit")
+	fs.StringVar(&zipName, "zip-name", "unknown.zip", "zip file name (stdin only)")
+	fs.StringVar(&format, "format", string(zj.ListNdjson), "list format: ndjson, csv or table")
+	fs.BoolVar(&stdinList, "stdin-list", false, "read the zip paths from stdin instead of a zip file")
+	fs.BoolVar(&nulList, "null", false, "the stdin paths are separated by NUL instead of newline")
+	fs.BoolVar(&strict, "strict", false, "abort on the first failed archive")
+	spill.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s list [flags] [zip file, directory or URL ...]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	e := fs.Parse(args)
+	if nil != e {
+		return e
+	}
+
+	listFormat, e := zj.ParseListFormat(format)
+	if nil != e {
+		return e
+	}
+	var stdout *bufio.Writer = bufio.NewWriter(os.Stdout)
+	lister, e := zj.NewEntryLister(stdout, listFormat)
+	if nil != e {
+		return e
+	}
+
+	var paths []string
+	switch {
+	case stdinList:
+		paths, e = readPathList(os.Stdin, nulList)
+	case 0 < fs.NArg():
+		paths, e = expandPaths(fs.Args(), zipExts, strict)
+	default:
+		arc, closer, err := spill.stdinArchive(zipSizeMax)
+		if nil != err {
+			return err
+		}
+		defer closer.Close() //nolint:errcheck // only the spill file is removed
+		e = lister.List(zipName, arc)
+	}
+	if nil != e {
+		return e
+	}
+
+	var failed int
+	for _, path := range paths {
+		e = listFile(lister, path, zipSizeMax)
+		if nil == e {
+			continue
+		}
+		if strict {
+			return e
+		}
+		failed++
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, e)
+	}
+
+	e = lister.Close()
+	if nil == e {
+		e = stdout.Flush()
+	}
+	if nil != e {
+		return e
+	}
+
+	if 0 < failed {
+		return fmt.Errorf("%d of %d archives failed", failed, len(paths))
+	}
+	return nil
+}
@@ -1,46 +1,402 @@
 package main
 
 import (
-	"encoding/json"
+	"bufio"
 	"flag"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"time"
 
 	zj "github.com/takanoriyanagitani/go-zip2blobs2jsons"
 )
 
+// Output modes of the -out-dir flag.
+const (
+	outModeArchive = "archive"
+	outModeEntry   = "entry"
+)
+
+// converter converts the archives using the processor template.
+type converter struct {
+	zipSizeMax int64
+	builder    zj.ZipBlobsBuilder
+	processor  zj.Processor
+	prefixed   bool
+	maxDict    int64
+
+	outDir      string
+	outMode     string
+	existPolicy zj.ExistPolicy
+	stems       *zj.StemSet
+
+	format     zj.OutputFormat
+	formatOpts zj.FormatOptions
+	escapeHTML bool
+
+	spill spillFlags
+}
+
+// stdoutSink creates the sink of the standard output, or none if the outputs go to the directory.
+func stdoutSink(w io.Writer, outDir string, format zj.OutputFormat, opts zj.FormatOptions) (zj.BlobSink, error) {
+	if "" != outDir {
+		return nil, nil
+	}
+	return zj.NewFormatSink(w, format, opts)
+}
+
+// closeStdoutSink completes the document of the standard output, if any.
+func closeStdoutSink(sink zj.BlobSink) error {
+	if nil == sink {
+		return nil
+	}
+	return zj.CloseSink(sink)
+}
+
+func (c converter) processorFor(zipName string) zj.Processor {
+	var bldr zj.ZipBlobsBuilder = c.builder
+	bldr.ZipName = zipName
+
+	var p zj.Processor = c.processor
+	p.Builder = bldr.ToBuilder()
+	return p
+}
+
+// output runs the process with the sink selected by the output flags.
+func (c converter) output(zipName string, process func(zj.Processor) error) error {
+	var p zj.Processor = c.processorFor(zipName)
+	if "" == c.outDir {
+		return process(p)
+	}
+
+	var stem string = c.stems.Stem(zipName)
+	if outModeEntry == c.outMode {
+		p.Sink = zj.EntryDirSink{
+			Dir:        filepath.Join(c.outDir, stem),
+			Policy:     c.existPolicy,
+			EscapeHTML: c.escapeHTML,
+		}
+		return process(p)
+	}
+
+	var name string = filepath.Join(c.outDir, stem+c.format.Extension())
+	_, e := zj.WriteFileAtomic(name, c.existPolicy, func(w io.Writer) error {
+		var bw *bufio.Writer = bufio.NewWriter(w)
+		sink, e := zj.NewFormatSink(bw, c.format, c.formatOpts)
+		if nil != e {
+			return e
+		}
+		p.Sink = sink
+		e = process(p)
+		if nil != e {
+			return e
+		}
+		e = zj.CloseSink(sink)
+		if nil != e {
+			return e
+		}
+		return bw.Flush()
+	})
+	return e
+}
+
+func (c converter) convertStdin(zipName string) error {
+	var reader zj.Reader = c.spill.reader(os.Stdin)
+	reader.Prefixed = c.prefixed
+	reader.MaxDict = c.maxDict
+	return c.output(zipName, func(p zj.Processor) error {
+		return reader.ProcessAuto(c.zipSizeMax, p)
+	})
+}
+
+func (c converter) convertFile(path string) error {
+	f, closer, e := openArchive(path)
+	if nil != e {
+		return e
+	}
+	defer closer.Close() //nolint:errcheck // the archive is read only
+
+	var head []byte = make([]byte, 512)
+	n, _ := f.ReadAt(head, 0)
+	head = head[:n]
+	if (zj.IsTarInput(head) || zj.IsSevenZip(head)) && c.zipSizeMax < f.Size {
+		return fmt.Errorf("could not read %s: %w: %d > %d", path, zj.ErrArchiveTooLarge, f.Size, c.zipSizeMax)
+	}
+	if zj.IsSevenZip(head) {
+		arc, e := f.To7z()
+		if nil != e {
+			return fmt.Errorf("could not read %s: %w", path, e)
+		}
+		arc.MaxDict = c.maxDict
+		return c.output(path, func(p zj.Processor) error {
+			return p.Process7z(arc)
+		})
+	}
+	if zj.IsTarInput(head) {
+		return c.output(path, func(p zj.Processor) error {
+			return p.ProcessTar(io.NewSectionReader(f, 0, f.Size))
+		})
+	}
+
+	arc, e := c.toZip(f)
+	if nil != e {
+		return fmt.Errorf("could not read %s: %w", path, e)
+	}
+	return c.output(path, func(p zj.Processor) error {
+		return p.Process(arc)
+	})
+}
+
+// toZip opens the zip file up to the size limit, tolerating data before it with -prefixed.
+func (c converter) toZip(f zj.FileLike) (zj.ZipArchive, error) {
+	if !c.prefixed {
+		return f.ToZipLimited(c.zipSizeMax)
+	}
+	if c.zipSizeMax < f.Size {
+		return zj.ZipArchive{}, fmt.Errorf("%w: %d > %d", zj.ErrArchiveTooLarge, f.Size, c.zipSizeMax)
+	}
+	return f.ToZipPrefixed()
+}
+
+// convertFiles converts the archives one by one.
+// Unless strict, a failed archive is reported and the remaining archives are converted.
+func (c converter) convertFiles(paths []string, strict bool) (int, error) {
+	var failed int
+	for _, path := range paths {
+		e := c.convertFile(path)
+		if nil == e {
+			continue
+		}
+		if strict {
+			return failed + 1, e
+		}
+		failed++
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, e)
+	}
+	return failed, nil
+}
+
 func main() {
+	var run func([]string) error
+	if 1 < len(os.Args) {
+		switch os.Args[1] {
+		case "list":
+			run = runList
+		case "extract":
+			run = runExtract
+		}
+	}
+	if nil != run {
+		e := run(os.Args[2:])
+		if nil != e {
+			fmt.Fprintln(os.Stderr, e)
+			os.Exit(1)
+		}
+		return
+	}
+
+	runConvert()
+}
+
+func runConvert() {
 	var zipSizeMax int64
 	var zipName string
 	var itemSizeMax int64
 	var itemContentType string
 	var itemContentEncoding string
+	var workers int
+	var maxInFlight int
+	var unordered bool
+	var itemTimeout time.Duration
+	var meta metaFlag = metaFlag{}
+	var derivedMeta bool
+	var markTruncated bool
+	var bracket bool
+	var stdinList bool
+	var nulList bool
+	var strict bool
+	var spill spillFlags
+	var outDir string
+	var outMode string
+	var exist string
+	var outputFormat string
+	var escapeHTML bool
+	var avroCodec string
+	var parquetRowGroupSize int
+	var parquetRowGroupBytes int64
+	var parquetBody bool
+	var names string
+	var duplicates string
+	var foldCase bool
+	var foldUnicode bool
+	var prefixed bool
+	var prefixItem bool
+	var maxDict int64
 
 	flag.Int64Var(&zipSizeMax, "zip-size-max", 10485760, "zip file size limit")
-	flag.StringVar(&zipName, "zip-name", "unknown.zip", "zip file name")
-	flag.Int64Var(&itemSizeMax, "item-size-max", 1048576, "zip item size limit")
+	flag.StringVar(&zipName, "zip-name", "unknown.zip", "archive name (stdin only); stdin may also be a 7z archive or a tar stream")
+	flag.Int64Var(&itemSizeMax, "item-size-max", 1048576, "zip item size limit: the (raw) bodies of larger items are cut")
 	flag.StringVar(&itemContentType, "item-content-type", "application/octet-stream", "item content type")
 	flag.StringVar(&itemContentEncoding, "item-content-encoding", "identity", "item content encoding")
+	flag.IntVar(&workers, "workers", 1, "number of items converted concurrently")
+	flag.IntVar(&maxInFlight, "max-in-flight", 0, "converted items held in memory (0: twice the workers)")
+	flag.BoolVar(&unordered, "unordered", false, "write items in completion order")
+	flag.DurationVar(&itemTimeout, "item-timeout", 0, "time limit for converting a single item (0: no limit)")
+	flag.Var(
+		meta,
+		"meta",
+		"item metadata as key=value (repeatable); values may use {{index}}, {{total}}, {{name}}, {{dir}}, {{base}}, {{ext}} and {{depth}}",
+	)
+	flag.BoolVar(&markTruncated, "mark-truncated", false, "add truncated=true to the metadata of the items larger than -item-size-max")
+	flag.BoolVar(&derivedMeta, "derived-meta", false, "add index, total, dir, base, ext and depth to the item metadata")
+	flag.BoolVar(&bracket, "bracket", false, "write archive header and trailer records around the items")
+	flag.BoolVar(&stdinList, "stdin-list", false, "read the zip paths from stdin instead of a zip file")
+	flag.BoolVar(&nulList, "null", false, "the stdin paths are separated by NUL instead of newline")
+	flag.BoolVar(&strict, "strict", false, "abort on the first failed archive")
+	spill.register(flag.CommandLine)
+	flag.StringVar(&outDir, "out-dir", "", "write the records to files in the directory instead of stdout")
+	flag.StringVar(&outMode, "out-mode", outModeArchive, "out-dir layout: archive (<zip>.<format>) or entry (<zip>/<item>.json)")
+	flag.StringVar(&exist, "exist", string(zj.ExistFail), "existing output files: fail, overwrite or skip")
+	flag.StringVar(&outputFormat, "output-format", string(zj.FormatNdjson), "output format: ndjson, pretty, array, object, cbor, msgpack, avro or parquet")
+	flag.BoolVar(&escapeHTML, "escape-html", true, "escape <, > and & in the JSON strings")
+	flag.StringVar(&avroCodec, "avro-codec", string(zj.AvroDeflate), "avro block compression: null or deflate")
+	flag.IntVar(&parquetRowGroupSize, "parquet-row-group-size", 4096, "rows of a parquet row group")
+	flag.Int64Var(&parquetRowGroupBytes, "parquet-row-group-bytes", 64<<20, "value bytes of a parquet row group")
+	flag.BoolVar(&parquetBody, "parquet-body", false, "add the item body as a parquet column")
+	flag.StringVar(&names, "names", "none", "unsafe item names: none, annotate (metadata name_findings), normalize or reject")
+	flag.StringVar(&duplicates, "duplicates", "none", "duplicate item names: none, all (metadata occurrence), first, last or fail")
+	flag.BoolVar(&foldCase, "duplicates-fold-case", false, "compare the names case-insensitively for -duplicates")
+	flag.BoolVar(&foldUnicode, "duplicates-fold-unicode", false, "compare the names in Unicode NFC for -duplicates")
+	flag.BoolVar(&prefixed, "prefixed", false, "accept data before the zip, such as self-extracting stubs; the header record reports its length")
+	flag.Int64Var(&maxDict, "7z-dict-max", 1<<28, "lzma dictionary size limit of the 7z folders")
+	flag.BoolVar(&prefixItem, "prefix-item", false, "write the data before the zip as a pseudo item named "+zj.PrefixName+" (with -prefixed)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] [zip, 7z or tar file, directory or URL ...]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s list [flags] [zip file, directory or URL ...]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s extract [flags] [zip file, directory or URL ...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
-	var reader zj.Reader = zj.Reader{
-		Reader: os.Stdin,
+	existPolicy, e := zj.ParseExistPolicy(exist)
+	if nil != e {
+		panic(e)
+	}
+	format, e := zj.ParseOutputFormat(outputFormat)
+	if nil != e {
+		panic(e)
+	}
+	codec, e := zj.ParseAvroCodec(avroCodec)
+	if nil != e {
+		panic(e)
+	}
+	namePolicy, e := zj.ParseNamePolicy(names)
+	if nil != e {
+		panic(e)
 	}
+	duplicatePolicy, e := zj.ParseDuplicatePolicy(duplicates)
+	if nil != e {
+		panic(e)
+	}
+	var fold zj.NameFold
+	if foldCase {
+		fold |= zj.FoldCase
+	}
+	if foldUnicode {
+		fold |= zj.FoldUnicode
+	}
+	var formatOpts zj.FormatOptions = zj.FormatOptions{
+		EscapeHTML: escapeHTML,
+		AvroCodec:  codec,
 
-	var encoder zj.JsonEncoder = zj.JsonEncoder{
-		Encoder: json.NewEncoder(os.Stdout),
+		ParquetRowGroupSize:  parquetRowGroupSize,
+		ParquetRowGroupBytes: parquetRowGroupBytes,
+		ParquetBody:          parquetBody,
+	}
+	if outModeArchive != outMode && outModeEntry != outMode {
+		panic("unknown out-mode: " + outMode)
+	}
+	if "" != outDir {
+		e = os.MkdirAll(outDir, 0o755)
+		if nil != e {
+			panic(e)
+		}
 	}
 
-	var builder zj.ZipBlobsBuilder = zj.ZipBlobsBuilder{
-		ZipName: zipName,
+	var stdout *bufio.Writer = bufio.NewWriter(os.Stdout)
+	encoder, e := stdoutSink(stdout, outDir, format, formatOpts)
+	if nil != e {
+		panic(e)
 	}
+
+	var builder zj.ZipBlobsBuilder
 	builder.MaxBytes = itemSizeMax
 	builder.ContentType = itemContentType
 	builder.ContentEncoding = itemContentEncoding
+	builder.Metadata = meta
+
+	var conv converter = converter{
+		zipSizeMax: zipSizeMax,
+		builder:    builder,
+		processor: zj.Processor{
+			Sink:        encoder,
+			Workers:     workers,
+			MaxInFlight: maxInFlight,
+			Unordered:   unordered,
+			ItemTimeout: itemTimeout,
+
+			DerivedMetadata: derivedMeta,
+			MarkTruncated:   markTruncated,
+			Bracket:         bracket,
+			Names:           namePolicy,
+			Duplicates:      duplicatePolicy,
+			DuplicateFold:   fold,
+			PrefixItem:      prefixItem,
+		},
+		prefixed: prefixed,
+		maxDict:  maxDict,
 
-	var bldr zj.BlobBuilder = builder.ToBuilder()
+		outDir:      outDir,
+		outMode:     outMode,
+		existPolicy: existPolicy,
+		stems:       &zj.StemSet{},
 
-	e := reader.ToJsons(zipSizeMax, encoder, bldr)
+		format:     format,
+		formatOpts: formatOpts,
+		escapeHTML: escapeHTML,
+
+		spill: spill,
+	}
+
+	var paths []string
+	switch {
+	case stdinList:
+		paths, e = readPathList(os.Stdin, nulList)
+	case 0 < flag.NArg():
+		paths, e = expandPaths(flag.Args(), archiveExts, strict)
+	default:
+		e = conv.convertStdin(zipName)
+	}
 	if nil != e {
 		panic(e)
 	}
+
+	failed, e := conv.convertFiles(paths, strict)
+	if nil != e {
+		panic(e)
+	}
+
+	e = closeStdoutSink(encoder)
+	if nil == e {
+		e = stdout.Flush()
+	}
+	if nil != e {
+		panic(e)
+	}
+
+	if 0 < failed {
+		fmt.Fprintf(os.Stderr, "%d of %d archives failed\n", failed, len(paths))
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	zj "github.com/takanoriyanagitani/go-zip2blobs2jsons"
+)
+
+func TestConverter_OutDirStdout(t *testing.T) {
+	t.Parallel()
+
+	for _, format := range []zj.OutputFormat{zj.FormatNdjson, zj.FormatArray, zj.FormatObject, zj.FormatAvro, zj.FormatParquet} {
+		t.Run(string(format), func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			stdout := new(bytes.Buffer)
+			sink, err := stdoutSink(stdout, dir, format, zj.FormatOptions{})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var builder zj.ZipBlobsBuilder
+			builder.MaxBytes = 1024
+			conv := converter{
+				zipSizeMax:  1 << 20,
+				builder:     builder,
+				processor:   zj.Processor{Sink: sink},
+				outDir:      dir,
+				outMode:     outModeArchive,
+				existPolicy: zj.ExistFail,
+				stems:       &zj.StemSet{},
+				format:      format,
+			}
+			err = conv.convertFile(filepath.Join("..", "..", "testdata.d", "hw.zip"))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			err = closeStdoutSink(sink)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if stdout.Len() != 0 {
+				t.Errorf("Expected an empty stdout, got %d bytes", stdout.Len())
+			}
+			_, err = os.Stat(filepath.Join(dir, "hw"+format.Extension()))
+			if err != nil {
+				t.Errorf("Expected the output file: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+//go:build !tinygo
+
+package main
+
+import (
+	"io"
+
+	zj "github.com/takanoriyanagitani/go-zip2blobs2jsons"
+)
+
+// openArchive maps a local file into memory, or reads a http(s) URL with range requests.
+// The segments of a split zip are joined.
+func openArchive(path string) (zj.FileLike, io.Closer, error) {
+	if !isURL(path) {
+		segments, e := splitSegments(path)
+		if nil != e {
+			return zj.FileLike{}, nil, e
+		}
+		if nil != segments {
+			return zj.OpenSplitZip(segments)
+		}
+		return zj.OpenMmapFileLike(path)
+	}
+
+	var h *zj.HTTPReaderAt = &zj.HTTPReaderAt{URL: path}
+	f, e := h.Open()
+	// the responses are closed by the reader: nothing is left open
+	return f, io.NopCloser(nil), e
+}
@@ -0,0 +1,26 @@
+//go:build tinygo
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	zj "github.com/takanoriyanagitani/go-zip2blobs2jsons"
+)
+
+// openArchive opens a local file, joining the segments of a split zip.
+// URLs are not supported without net/http.
+func openArchive(path string) (zj.FileLike, io.Closer, error) {
+	if isURL(path) {
+		return zj.FileLike{}, nil, fmt.Errorf("urls are not supported: %s", path)
+	}
+	segments, e := splitSegments(path)
+	if nil != e {
+		return zj.FileLike{}, nil, e
+	}
+	if nil != segments {
+		return zj.OpenSplitZip(segments)
+	}
+	return zj.OpenFileLike(path)
+}
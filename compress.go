@@ -0,0 +1,97 @@
+package zip2jsons
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Compression is the compression of a whole input stream, such as a tar.gz.
+type Compression string
+
+// Compressions.
+const (
+	CompressionNone  Compression = ""
+	CompressionGzip  Compression = "gzip"
+	CompressionBzip2 Compression = "bzip2"
+	CompressionXz    Compression = "xz"
+	CompressionZstd  Compression = "zstd"
+)
+
+// compressionMagic is the number of bytes needed by DetectCompression.
+const compressionMagic = 6
+
+var magics = []struct {
+	magic       []byte
+	compression Compression
+}{
+	{[]byte{0x1f, 0x8b}, CompressionGzip},
+	{[]byte("BZh"), CompressionBzip2},
+	{[]byte{0xfd, '7', 'z', 'X', 'Z', 0x00}, CompressionXz},
+	{[]byte{0x28, 0xb5, 0x2f, 0xfd}, CompressionZstd},
+}
+
+// DetectCompression detects the compression from the magic bytes at the start of the input.
+func DetectCompression(head []byte) Compression {
+	for _, m := range magics {
+		if bytes.HasPrefix(head, m.magic) {
+			return m.compression
+		}
+	}
+	return CompressionNone
+}
+
+// Decompress returns the decompressed stream.
+// The Closer releases the resources of the decompressor, not the underlying reader.
+func Decompress(r io.Reader, c Compression) (io.Reader, io.Closer, error) {
+	switch c {
+	case CompressionNone:
+		return r, io.NopCloser(nil), nil
+	case CompressionGzip:
+		gz, e := gzip.NewReader(r)
+		if nil != e {
+			return nil, nil, fmt.Errorf("could not read gzip: %w", e)
+		}
+		return gz, gz, nil
+	case CompressionBzip2:
+		return bzip2.NewReader(r), io.NopCloser(nil), nil
+	case CompressionXz:
+		x, e := xz.NewReader(r)
+		if nil != e {
+			return nil, nil, fmt.Errorf("could not read xz: %w", e)
+		}
+		return x, io.NopCloser(nil), nil
+	case CompressionZstd:
+		z, e := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if nil != e {
+			return nil, nil, fmt.Errorf("could not read zstd: %w", e)
+		}
+		return z, z.IOReadCloser(), nil
+	default:
+		return nil, nil, fmt.Errorf("%w: compression %s", ErrUnsupported, c)
+	}
+}
+
+// tarHeaderSize is the number of bytes needed by IsTar.
+const tarHeaderSize = 512
+
+// IsTar reports whether the (uncompressed) input starting with head is a ustar, pax or GNU tar archive.
+func IsTar(head []byte) bool {
+	if len(head) < tarHeaderSize {
+		return false
+	}
+	var magic []byte = head[257:263]
+	return bytes.Equal(magic, []byte("ustar\x00")) || bytes.Equal(magic, []byte("ustar "))
+}
+
+// IsTarInput reports whether the input starting with head is read as a tar stream,
+// that is a tar archive or a compressed input.
+// The head should hold at least the first 512 bytes of the input.
+func IsTarInput(head []byte) bool {
+	return CompressionNone != DetectCompression(head) || IsTar(head)
+}
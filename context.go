@@ -0,0 +1,27 @@
+package zip2jsons
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+func canceled(ctx context.Context) error {
+	return fmt.Errorf("%w: %w", ErrCanceled, ctx.Err())
+}
+
+// ContextReader fails the reads once the context is done.
+type ContextReader struct {
+	io.Reader
+
+	Context context.Context
+}
+
+// Read implements io.Reader.
+func (c ContextReader) Read(p []byte) (int, error) {
+	e := c.Context.Err()
+	if nil != e {
+		return 0, e
+	}
+	return c.Reader.Read(p)
+}
@@ -0,0 +1,110 @@
+package zip2jsons_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+	"github.com/takanoriyanagitani/go-zip2blobs2jsons"
+)
+
+func TestProcessor_ProcessContext(t *testing.T) {
+	t.Parallel()
+
+	var bldr bj.BlobBuilder = bj.BlobBuilder{MaxBytes: 1024}
+
+	t.Run("canceled before processing", func(t *testing.T) {
+		t.Parallel()
+
+		for _, workers := range []int{1, 4} {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			var sink zip2jsons.SliceSink
+			var proc zip2jsons.Processor = zip2jsons.Processor{Sink: &sink, Builder: bldr, Workers: workers}
+			err := proc.ProcessContext(ctx, newTestArchive(t, manyItems(10)...))
+			if !errors.Is(err, zip2jsons.ErrCanceled) || !errors.Is(err, context.Canceled) {
+				t.Errorf("Expected canceled error with %d workers, got %v", workers, err)
+			}
+			if len(sink.Blobs) != 0 {
+				t.Errorf("Expected no blobs with %d workers, got %d", workers, len(sink.Blobs))
+			}
+		}
+	})
+
+	t.Run("canceled between items", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		written := 0
+		var sink zip2jsons.BlobSinkFn = func(_ *bj.Blob) error {
+			written++
+			if written == 3 {
+				cancel()
+			}
+			return nil
+		}
+		err := zip2jsons.ProcessZipArchiveContext(ctx, newTestArchive(t, manyItems(10)...), sink, bldr)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected canceled error, got %v", err)
+		}
+		if written != 3 {
+			t.Errorf("Expected 3 writes, got %d", written)
+		}
+	})
+
+	t.Run("item timeout", func(t *testing.T) {
+		t.Parallel()
+
+		var proc zip2jsons.Processor = zip2jsons.Processor{
+			Sink:        &zip2jsons.SliceSink{},
+			Builder:     bldr,
+			ItemTimeout: time.Nanosecond,
+		}
+		err := proc.Process(newTestArchive(t, manyItems(1)...))
+		if !errors.Is(err, zip2jsons.ErrCanceled) || !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected deadline error, got %v", err)
+		}
+	})
+}
+
+func TestContextReader(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var rdr zip2jsons.ContextReader = zip2jsons.ContextReader{
+		Reader:  strings.NewReader("hello"),
+		Context: ctx,
+	}
+
+	buf := make([]byte, 2)
+	_, err := rdr.Read(buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	cancel()
+	_, err = rdr.Read(buf)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected canceled error, got %v", err)
+	}
+}
+
+func TestReader_ToJsonsContext(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var rdr zip2jsons.Reader = zip2jsons.Reader{Reader: bytes.NewReader(nil)}
+	err := rdr.ToJsonsContext(ctx, 1024, &zip2jsons.SliceSink{}, bj.BlobBuilder{})
+	if !errors.Is(err, zip2jsons.ErrCanceled) {
+		t.Errorf("Expected canceled error, got %v", err)
+	}
+}
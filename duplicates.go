@@ -0,0 +1,121 @@
+package zip2jsons
+
+import (
+	"fmt"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// DuplicatePolicy decides how the Processor handles item names appearing more than once.
+type DuplicatePolicy string
+
+// Duplicate policies.
+const (
+	// DuplicatesNone converts every item without looking for duplicates.
+	DuplicatesNone DuplicatePolicy = ""
+
+	// DuplicatesAll converts every item and adds the zero-based occurrence
+	// of the duplicated names to the metadata under MetaOccurrence.
+	DuplicatesAll DuplicatePolicy = "all"
+
+	// DuplicatesFirst converts the first occurrence of each name only.
+	DuplicatesFirst DuplicatePolicy = "first"
+
+	// DuplicatesLast converts the last occurrence of each name only.
+	DuplicatesLast DuplicatePolicy = "last"
+
+	// DuplicatesFail fails the archive with ErrDuplicateName before any item is written.
+	DuplicatesFail DuplicatePolicy = "fail"
+)
+
+// MetaOccurrence is the metadata key of the occurrence of a duplicated name.
+const MetaOccurrence = "occurrence"
+
+// ParseDuplicatePolicy parses the name of a DuplicatePolicy. "none" is DuplicatesNone.
+func ParseDuplicatePolicy(s string) (DuplicatePolicy, error) {
+	switch p := DuplicatePolicy(s); p {
+	case "none":
+		return DuplicatesNone, nil
+	case DuplicatesNone, DuplicatesAll, DuplicatesFirst, DuplicatesLast, DuplicatesFail:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown duplicate policy: %s", s)
+	}
+}
+
+// NameFold selects how the names are folded before they are compared.
+type NameFold int
+
+// Name folds.
+const (
+	// FoldCase compares the names case-insensitively, using Unicode case folding.
+	FoldCase NameFold = 1 << iota
+
+	// FoldUnicode compares the names in Unicode normalization form C,
+	// so that precomposed and decomposed characters are the same.
+	FoldUnicode
+)
+
+// Key returns the folded name.
+func (f NameFold) Key(name string) string {
+	if 0 != f&FoldUnicode {
+		name = norm.NFC.String(name)
+	}
+	if 0 != f&FoldCase {
+		name = cases.Fold().String(name)
+	}
+	return name
+}
+
+// duplicateKey returns the key comparing the name for Duplicates.
+// The names are compared as they are written, so normalized if the policy says so.
+func (p Processor) duplicateKey(name string) string {
+	if NamesNormalize == p.Names {
+		name = NormalizeName(name)
+	}
+	return p.DuplicateFold.Key(name)
+}
+
+// duplicates selects the items to convert and numbers the occurrences of the duplicated names.
+// The selected indexes are in central-directory order.
+func (p Processor) duplicates(names []string) ([]int, map[int]int, error) {
+	var groups map[string][]int = make(map[string][]int, len(names))
+	var keys []string = make([]string, len(names))
+	for i, name := range names {
+		keys[i] = p.duplicateKey(name)
+		groups[keys[i]] = append(groups[keys[i]], i)
+	}
+
+	var selected []int = make([]int, 0, len(names))
+	var occurrences map[int]int = map[int]int{}
+	// seen counts the occurrences of each key so far
+	var seen map[string]int = map[string]int{}
+	for i, name := range names {
+		var group []int = groups[keys[i]]
+		if len(group) < 2 {
+			selected = append(selected, i)
+			continue
+		}
+
+		switch p.Duplicates {
+		case DuplicatesFail:
+			return nil, nil, fmt.Errorf(
+				"%w: %q appears %d times", ErrDuplicateName, name, len(group),
+			)
+		case DuplicatesFirst:
+			if group[0] == i {
+				selected = append(selected, i)
+			}
+		case DuplicatesLast:
+			if group[len(group)-1] == i {
+				selected = append(selected, i)
+			}
+		default:
+			occurrences[i] = seen[keys[i]]
+			seen[keys[i]]++
+			selected = append(selected, i)
+		}
+	}
+	return selected, occurrences, nil
+}
@@ -0,0 +1,177 @@
+package zip2jsons_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+	"github.com/takanoriyanagitani/go-zip2blobs2jsons"
+)
+
+func TestNameFold_Key(t *testing.T) {
+	t.Parallel()
+
+	decomposed := "cafe\u0301.txt"
+	precomposed := "caf\u00e9.txt"
+
+	if zip2jsons.NameFold(0).Key("A.txt") == zip2jsons.NameFold(0).Key("a.txt") {
+		t.Errorf("Expected case-sensitive keys without folding")
+	}
+	if zip2jsons.FoldCase.Key("Straße.TXT") != zip2jsons.FoldCase.Key("STRASSE.txt") {
+		t.Errorf("Expected case-folded keys to match")
+	}
+	if zip2jsons.FoldUnicode.Key(decomposed) != zip2jsons.FoldUnicode.Key(precomposed) {
+		t.Errorf("Expected normalized keys to match")
+	}
+	if zip2jsons.FoldCase.Key(decomposed) == zip2jsons.FoldCase.Key(precomposed) {
+		t.Errorf("Expected unnormalized keys to differ")
+	}
+}
+
+func TestProcessor_Duplicates(t *testing.T) {
+	t.Parallel()
+
+	pairs := []string{"a.txt", "first", "b.txt", "only", "A.txt", "second", "a.txt", "third"}
+	bldr := bj.BlobBuilder{MaxBytes: 1024}
+
+	process := func(t *testing.T, p zip2jsons.Processor) []*bj.Blob {
+		t.Helper()
+
+		var sink zip2jsons.SliceSink
+		p.Sink = &sink
+		p.Builder = bldr
+		err := p.Process(newTestArchive(t, pairs...))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return sink.Blobs
+	}
+
+	bodies := func(blobs []*bj.Blob) []string {
+		var got []string
+		for _, b := range blobs {
+			got = append(got, b.Body)
+		}
+		return got
+	}
+
+	cases := []struct {
+		name      string
+		processor zip2jsons.Processor
+		expected  []string
+	}{
+		{"first", zip2jsons.Processor{Duplicates: zip2jsons.DuplicatesFirst}, []string{"Zmlyc3Q=", "b25seQ==", "c2Vjb25k"}},
+		{"last", zip2jsons.Processor{Duplicates: zip2jsons.DuplicatesLast}, []string{"b25seQ==", "c2Vjb25k", "dGhpcmQ="}},
+		{
+			"first folded",
+			zip2jsons.Processor{Duplicates: zip2jsons.DuplicatesFirst, DuplicateFold: zip2jsons.FoldCase},
+			[]string{"Zmlyc3Q=", "b25seQ=="},
+		},
+		{
+			"last folded in parallel",
+			zip2jsons.Processor{Duplicates: zip2jsons.DuplicatesLast, DuplicateFold: zip2jsons.FoldCase, Workers: 3},
+			[]string{"b25seQ==", "dGhpcmQ="},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := bodies(process(t, tc.processor))
+			if len(got) != len(tc.expected) {
+				t.Fatalf("Expected %v, got %v", tc.expected, got)
+			}
+			for i := range got {
+				if got[i] != tc.expected[i] {
+					t.Errorf("Expected %v, got %v", tc.expected, got)
+				}
+			}
+		})
+	}
+
+	t.Run("all", func(t *testing.T) {
+		t.Parallel()
+
+		blobs := process(t, zip2jsons.Processor{Duplicates: zip2jsons.DuplicatesAll})
+		expected := []string{"0", "", "", "1"}
+		for i, b := range blobs {
+			var meta map[string]string
+			if len(b.Metadata) != 0 {
+				err := json.Unmarshal(b.Metadata, &meta)
+				if err != nil {
+					t.Fatalf("Invalid metadata: %v", err)
+				}
+			}
+			if meta[zip2jsons.MetaOccurrence] != expected[i] {
+				t.Errorf("%d: expected occurrence %q, got %q", i, expected[i], meta[zip2jsons.MetaOccurrence])
+			}
+		}
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		t.Parallel()
+
+		var sink zip2jsons.SliceSink
+		p := zip2jsons.Processor{Sink: &sink, Builder: bldr, Duplicates: zip2jsons.DuplicatesFail}
+		err := p.Process(newTestArchive(t, pairs...))
+		if !errors.Is(err, zip2jsons.ErrDuplicateName) {
+			t.Errorf("Expected ErrDuplicateName, got %v", err)
+		}
+		if len(sink.Blobs) != 0 {
+			t.Errorf("Expected nothing written, got %d blobs", len(sink.Blobs))
+		}
+	})
+
+	t.Run("skipped in trailer", func(t *testing.T) {
+		t.Parallel()
+
+		blobs := process(t, zip2jsons.Processor{Duplicates: zip2jsons.DuplicatesFirst, Bracket: true})
+		var trailer zip2jsons.ArchiveTrailer
+		err := json.Unmarshal(blobs[len(blobs)-1].Metadata, &trailer)
+		if err != nil {
+			t.Fatalf("Invalid trailer: %v", err)
+		}
+		if trailer.Skipped != 1 || trailer.Items != 3 {
+			t.Errorf("Expected 3 items and 1 skipped, got %+v", trailer)
+		}
+	})
+}
+
+func TestProcessor_DuplicatesNormalized(t *testing.T) {
+	t.Parallel()
+
+	pairs := []string{"a/b.txt", "first", `a\b.txt`, "second", "c.txt", "third", "./c.txt", "fourth"}
+	inputs := map[string]func(p zip2jsons.Processor) error{
+		"zip": func(p zip2jsons.Processor) error { return p.Process(newTestArchive(t, pairs...)) },
+		"tar": func(p zip2jsons.Processor) error { return p.ProcessTar(bytes.NewReader(newTarBytes(t, pairs...))) },
+	}
+	for name, process := range inputs {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var sink zip2jsons.SliceSink
+			p := zip2jsons.Processor{
+				Sink:       &sink,
+				Builder:    bj.BlobBuilder{MaxBytes: 1024},
+				Names:      zip2jsons.NamesNormalize,
+				Duplicates: zip2jsons.DuplicatesFail,
+			}
+			err := process(p)
+			if !errors.Is(err, zip2jsons.ErrDuplicateName) {
+				t.Errorf("Expected ErrDuplicateName, got %v", err)
+			}
+
+			sink = zip2jsons.SliceSink{}
+			p.Duplicates = zip2jsons.DuplicatesFirst
+			err = process(p)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(sink.Blobs) != 2 || sink.Blobs[0].Name != "a/b.txt" || sink.Blobs[1].Name != "c.txt" {
+				t.Errorf("Unexpected blobs: %d", len(sink.Blobs))
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package zip2jsons
+
+import (
+	"archive/zip"
+	"strconv"
+	"time"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+)
+
+// Entry describes a zip item as recorded in the central directory.
+type Entry struct {
+	Name           string    `json:"name"`
+	Size           uint64    `json:"size"`
+	CompressedSize uint64    `json:"compressed_size"`
+	CRC32          uint32    `json:"crc32"`
+	Modified       time.Time `json:"modified"`
+	Method         uint16    `json:"method"`
+	ExternalAttrs  uint32    `json:"external_attrs"`
+	Mode           string    `json:"mode"`
+	Comment        string    `json:"comment"`
+}
+
+// NewEntry creates an Entry from the header of a zip item.
+func NewEntry(h *zip.FileHeader) Entry {
+	return Entry{
+		Name:           h.Name,
+		Size:           h.UncompressedSize64,
+		CompressedSize: h.CompressedSize64,
+		CRC32:          h.CRC32,
+		Modified:       h.Modified,
+		Method:         h.Method,
+		ExternalAttrs:  h.ExternalAttrs,
+		Mode:           h.Mode().String(),
+		Comment:        h.Comment,
+	}
+}
+
+// MethodName returns the name of the compression method.
+func (e Entry) MethodName() string {
+	switch e.Method {
+	case zip.Store:
+		return "store"
+	case zip.Deflate:
+		return "deflate"
+	default:
+		return "method(" + strconv.Itoa(int(e.Method)) + ")"
+	}
+}
+
+// Entries returns the central directory entries without reading the item data.
+func (a ZipArchive) Entries() []Entry {
+	var files []*zip.File = a.Files()
+	var entries []Entry = make([]Entry, 0, len(files))
+	for _, f := range files {
+		entries = append(entries, NewEntry(&f.FileHeader))
+	}
+	return entries
+}
+
+// Entry returns the central directory entry of the zip item.
+func (i ZipItem) Entry() Entry { return NewEntry(&i.File.FileHeader) }
+
+// EntrySink is implemented by sinks which also use the central directory entry of the items.
+// The Processor calls WriteEntry instead of Write for the items of an archive.
+type EntrySink interface {
+	BlobSink
+
+	WriteEntry(Entry, *bj.Blob) error
+}
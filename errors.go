@@ -5,3 +5,37 @@ import "errors"
 // ErrNewReader indicates a failure to create a new zip reader, often due to invalid
 // or truncated zip data.
 var ErrNewReader = errors.New("failed to create new zip reader")
+
+// ErrCanceled indicates that the processing was aborted by its context.
+// The error also wraps context.Canceled or context.DeadlineExceeded.
+var ErrCanceled = errors.New("processing canceled")
+
+// ErrArchiveTooLarge indicates that the archive exceeds the size limit.
+var ErrArchiveTooLarge = errors.New("archive too large")
+
+// ErrUnsafeName indicates an item name which could escape the target directory.
+var ErrUnsafeName = errors.New("unsafe item name")
+
+// ErrFileExists indicates that an output file already exists.
+var ErrFileExists = errors.New("file already exists")
+
+// ErrBudgetExceeded indicates an item exceeding the size or compression ratio budget.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// ErrDuplicateName indicates an item name appearing more than once in an archive.
+var ErrDuplicateName = errors.New("duplicate item name")
+
+// ErrRangeRequest indicates that the server did not answer a range request as expected.
+var ErrRangeRequest = errors.New("range request failed")
+
+// ErrUnsupported indicates an input or an option not supported for the input format.
+var ErrUnsupported = errors.New("unsupported")
+
+// ErrInvalid7z indicates invalid or truncated 7z headers.
+var ErrInvalid7z = errors.New("invalid 7z archive")
+
+// ErrChecksum indicates a content or a header not matching its CRC.
+var ErrChecksum = errors.New("checksum mismatch")
+
+// ErrMissingSegment indicates a split archive missing some of its segments.
+var ErrMissingSegment = errors.New("missing segment")
@@ -0,0 +1,339 @@
+package zip2jsons
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+)
+
+// maxLinkTarget limits the size of a symlink target read from an archive.
+const maxLinkTarget = 4096
+
+// Extractor writes the items of archives to files below Dir.
+//
+// Item names are joined with SafeJoin, and nothing is written through a symlink.
+// Symlinks are created after all the other items,
+// and only if their targets stay below Dir without passing through another symlink.
+type Extractor struct {
+	Dir    string
+	Policy ExistPolicy
+
+	// MaxBytes limits the size of a single item (0: no limit).
+	MaxBytes int64
+
+	// MaxTotal limits the bytes written by the extractor (0: no limit).
+	MaxTotal int64
+
+	// MaxRatio limits the ratio of the uncompressed to the compressed size of an item (0: no limit).
+	MaxRatio float64
+
+	written int64
+}
+
+type extractedDir struct {
+	name     string
+	perm     fs.FileMode
+	modified time.Time
+}
+
+type extractedLink struct {
+	name   string
+	path   string
+	target string
+}
+
+// budget reserves the size of an item.
+func (x *Extractor) budget(name string, size, compressed uint64) error {
+	switch {
+	case 0 < x.MaxBytes && uint64(x.MaxBytes) < size:
+		return fmt.Errorf("%w: %s has %d bytes, limit %d", ErrBudgetExceeded, name, size, x.MaxBytes)
+	case 0 < x.MaxTotal && uint64(x.MaxTotal-x.written) < size:
+		return fmt.Errorf("%w: %s exceeds the total limit %d", ErrBudgetExceeded, name, x.MaxTotal)
+	case 0 < x.MaxRatio && 0 < size && float64(compressed)*x.MaxRatio < float64(size):
+		return fmt.Errorf(
+			"%w: %s has %d bytes compressed to %d, ratio limit %g",
+			ErrBudgetExceeded, name, size, compressed, x.MaxRatio,
+		)
+	}
+	x.written += int64(size) //nolint:gosec // checked against the limits
+	return nil
+}
+
+// target joins the name to Dir and checks that its parents are not symlinks.
+func (x *Extractor) target(name string) (string, error) {
+	joined, e := SafeJoin(x.Dir, name)
+	if nil != e {
+		return "", e
+	}
+
+	rel, e := filepath.Rel(x.Dir, filepath.Dir(joined))
+	if nil != e {
+		return "", fmt.Errorf("could not check %s: %w", joined, e)
+	}
+	if "." == rel {
+		return joined, nil
+	}
+
+	var current string = x.Dir
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		info, e := os.Lstat(current)
+		switch {
+		case errors.Is(e, fs.ErrNotExist):
+			return joined, nil
+		case nil != e:
+			return "", fmt.Errorf("could not check %s: %w", current, e)
+		case 0 != info.Mode()&fs.ModeSymlink:
+			return "", fmt.Errorf("%w: %q passes through the symlink %s", ErrUnsafeName, name, current)
+		}
+	}
+	return joined, nil
+}
+
+// writeFile writes the content atomically and restores the permissions and the modification time.
+func (x *Extractor) writeFile(name string, perm fs.FileMode, modified time.Time, content io.Reader) error {
+	e := os.MkdirAll(filepath.Dir(name), 0o755)
+	if nil != e {
+		return fmt.Errorf("could not create directory for %s: %w", name, e)
+	}
+
+	written, e := WriteFileAtomic(name, x.Policy, func(w io.Writer) error {
+		_, e := io.Copy(w, content)
+		return e
+	})
+	if nil != e || !written {
+		return e
+	}
+
+	if 0 != perm {
+		e = os.Chmod(name, perm)
+		if nil != e {
+			return fmt.Errorf("could not change the mode of %s: %w", name, e)
+		}
+	}
+	if !modified.IsZero() {
+		e = os.Chtimes(name, modified, modified)
+		if nil != e {
+			return fmt.Errorf("could not change the times of %s: %w", name, e)
+		}
+	}
+	return nil
+}
+
+func (x *Extractor) extractFile(ctx context.Context, f *zip.File, name string) error {
+	e := x.budget(f.Name, f.UncompressedSize64, f.CompressedSize64)
+	if nil != e {
+		return e
+	}
+
+	rc, e := f.Open()
+	if nil != e {
+		return fmt.Errorf("could not open zip file %s: %w", f.Name, e)
+	}
+	defer rc.Close() //nolint:errcheck // the "file" is read only
+
+	// archive/zip fails the read if the item is larger than its declared size
+	return x.writeFile(name, f.Mode().Perm(), f.Modified, ContextReader{Reader: rc, Context: ctx})
+}
+
+func readLink(f *zip.File) (string, error) {
+	rc, e := f.Open()
+	if nil != e {
+		return "", fmt.Errorf("could not open zip file %s: %w", f.Name, e)
+	}
+	defer rc.Close() //nolint:errcheck // the "file" is read only
+
+	target, e := io.ReadAll(io.LimitReader(rc, maxLinkTarget+1))
+	switch {
+	case nil != e:
+		return "", fmt.Errorf("could not read the symlink %s: %w", f.Name, e)
+	case maxLinkTarget < len(target):
+		return "", fmt.Errorf("%w: the symlink %s is too long", ErrUnsafeName, f.Name)
+	}
+	return string(target), nil
+}
+
+// checkLinkTarget walks the target from the directory of the link.
+// The target must stay below the root and must not pass through any symlink.
+func checkLinkTarget(name, target string, isLink func(string) bool) error {
+	switch {
+	case "" == target,
+		strings.HasPrefix(target, "/"),
+		hasDriveLetter(target),
+		strings.ContainsAny(target, "\\\x00"):
+		return fmt.Errorf("%w: the symlink %q points to %q", ErrUnsafeName, name, target)
+	}
+
+	var parts []string
+	var dir string = path.Dir(path.Clean(name))
+	if "." != dir {
+		parts = strings.Split(dir, "/")
+	}
+	for _, part := range strings.Split(target, "/") {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if 0 == len(parts) {
+				return fmt.Errorf("%w: the symlink %q escapes to %q", ErrUnsafeName, name, target)
+			}
+			parts = parts[:len(parts)-1]
+			continue
+		}
+		parts = append(parts, part)
+		if isLink(strings.Join(parts, "/")) {
+			return fmt.Errorf("%w: the symlink %q points through another symlink", ErrUnsafeName, name)
+		}
+	}
+	return nil
+}
+
+func (x *Extractor) isLink(links map[string]bool) func(string) bool {
+	return func(name string) bool {
+		if links[name] {
+			return true
+		}
+		info, e := os.Lstat(filepath.Join(x.Dir, filepath.FromSlash(name)))
+		return nil == e && 0 != info.Mode()&fs.ModeSymlink
+	}
+}
+
+func (x *Extractor) symlink(link extractedLink) error {
+	_, e := os.Lstat(link.path)
+	switch {
+	case nil == e && ExistSkip == x.Policy:
+		return nil
+	case nil == e && ExistOverwrite == x.Policy:
+		e = os.Remove(link.path)
+		if nil != e {
+			return fmt.Errorf("could not remove %s: %w", link.path, e)
+		}
+	case nil == e:
+		return fmt.Errorf("%w: %s", ErrFileExists, link.path)
+	case !errors.Is(e, fs.ErrNotExist):
+		return fmt.Errorf("could not check %s: %w", link.path, e)
+	}
+
+	e = os.MkdirAll(filepath.Dir(link.path), 0o755)
+	if nil != e {
+		return fmt.Errorf("could not create directory for %s: %w", link.path, e)
+	}
+	e = os.Symlink(filepath.FromSlash(link.target), link.path)
+	if nil != e {
+		return fmt.Errorf("could not create the symlink %s: %w", link.path, e)
+	}
+	return nil
+}
+
+// Extract writes the items of the archive below Dir.
+func (x *Extractor) Extract(arc ZipArchive) error {
+	return x.ExtractContext(context.Background(), arc)
+}
+
+// ExtractContext is like Extract but stops once the context is done.
+func (x *Extractor) ExtractContext(ctx context.Context, arc ZipArchive) error {
+	var dirs []extractedDir
+	var links []extractedLink
+	var linkNames map[string]bool = map[string]bool{}
+
+	for _, f := range arc.Files() {
+		if nil != ctx.Err() {
+			return canceled(ctx)
+		}
+
+		name, e := x.target(f.Name)
+		if nil != e {
+			return e
+		}
+
+		var mode fs.FileMode = f.Mode()
+		switch {
+		case mode.IsDir():
+			e = os.MkdirAll(name, 0o755)
+			if nil != e {
+				return fmt.Errorf("could not create directory %s: %w", name, e)
+			}
+			dirs = append(dirs, extractedDir{name: name, perm: mode.Perm(), modified: f.Modified})
+		case 0 != mode&fs.ModeSymlink:
+			target, e := readLink(f)
+			if nil != e {
+				return e
+			}
+			links = append(links, extractedLink{name: f.Name, path: name, target: target})
+			linkNames[path.Clean(f.Name)] = true
+		case mode.IsRegular():
+			e = x.extractFile(ctx, f, name)
+			if nil != e {
+				return fmt.Errorf("could not extract %s: %w", f.Name, e)
+			}
+		default:
+			return fmt.Errorf("could not extract %s: unsupported file type %s", f.Name, mode.Type())
+		}
+	}
+
+	for _, link := range links {
+		e := checkLinkTarget(link.name, link.target, x.isLink(linkNames))
+		if nil == e {
+			e = x.symlink(link)
+		}
+		if nil != e {
+			return e
+		}
+	}
+
+	// the children are done: restore the directories from the deepest one
+	for i := len(dirs) - 1; 0 <= i; i-- {
+		var dir extractedDir = dirs[i]
+		e := os.Chmod(dir.name, dir.perm|0o700)
+		if nil == e && !dir.modified.IsZero() {
+			e = os.Chtimes(dir.name, dir.modified, dir.modified)
+		}
+		if nil != e {
+			return fmt.Errorf("could not restore directory %s: %w", dir.name, e)
+		}
+	}
+	return nil
+}
+
+// Write implements BlobSink and extracts a blob, such as one decoded from the NDJSON output.
+// The body must be base64 encoded; truncated blobs are written as they are.
+func (x *Extractor) Write(b *bj.Blob) error {
+	name, e := x.target(b.Name)
+	if nil != e {
+		return e
+	}
+
+	if strings.HasSuffix(b.Name, "/") {
+		e = os.MkdirAll(name, 0o755)
+		if nil != e {
+			return fmt.Errorf("could not create directory %s: %w", name, e)
+		}
+		return nil
+	}
+
+	body, e := blobBody(b)
+	if nil != e {
+		return e
+	}
+	e = x.budget(b.Name, uint64(len(body)), uint64(len(body)))
+	if nil != e {
+		return e
+	}
+
+	var modified time.Time
+	if nil != b.LastModified {
+		modified = *b.LastModified
+	}
+	return x.writeFile(name, 0, modified, bytes.NewReader(body))
+}
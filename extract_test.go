@@ -0,0 +1,188 @@
+package zip2jsons_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+	"github.com/takanoriyanagitani/go-zip2blobs2jsons"
+)
+
+type testEntry struct {
+	name    string
+	mode    fs.FileMode
+	content string
+}
+
+// newModeArchive creates an in-memory zip archive with the file modes.
+func newModeArchive(t *testing.T, modified time.Time, entries ...testEntry) zip2jsons.ZipArchive {
+	t.Helper()
+
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for _, entry := range entries {
+		hdr := &zip.FileHeader{Name: entry.name, Method: zip.Deflate, Modified: modified}
+		hdr.SetMode(entry.mode)
+		f, err := w.CreateHeader(hdr)
+		if err != nil {
+			t.Fatalf("Failed to create %s: %v", entry.name, err)
+		}
+		_, err = f.Write([]byte(entry.content))
+		if err != nil {
+			t.Fatalf("Failed to write %s: %v", entry.name, err)
+		}
+	}
+	err := w.Close()
+	if err != nil {
+		t.Fatalf("Failed to close zip writer: %v", err)
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Failed to create zip reader: %v", err)
+	}
+	return zip2jsons.ZipArchive{Reader: r}
+}
+
+func TestExtractor_Extract(t *testing.T) {
+	t.Parallel()
+
+	modified := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	t.Run("files and directories", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		arc := newModeArchive(t, modified,
+			testEntry{name: "d/", mode: fs.ModeDir | 0o750},
+			testEntry{name: "d/run.sh", mode: 0o755, content: "#!/bin/sh\n"},
+			testEntry{name: "d/link", mode: fs.ModeSymlink | 0o777, content: "run.sh"},
+		)
+		x := zip2jsons.Extractor{Dir: dir}
+		err := x.Extract(arc)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		info, err := os.Stat(filepath.Join(dir, "d", "run.sh"))
+		if err != nil {
+			t.Fatalf("Failed to stat: %v", err)
+		}
+		if info.Mode().Perm() != 0o755 || !info.ModTime().Equal(modified) {
+			t.Errorf("Expected 0755 modified at %v, got %v at %v", modified, info.Mode(), info.ModTime())
+		}
+
+		info, err = os.Stat(filepath.Join(dir, "d"))
+		if err != nil {
+			t.Fatalf("Failed to stat: %v", err)
+		}
+		if info.Mode().Perm() != 0o750 || !info.ModTime().Equal(modified) {
+			t.Errorf("Expected 0750 modified at %v, got %v at %v", modified, info.Mode(), info.ModTime())
+		}
+
+		target, err := os.Readlink(filepath.Join(dir, "d", "link"))
+		if err != nil || target != "run.sh" {
+			t.Errorf("Expected a symlink to run.sh, got %q (%v)", target, err)
+		}
+	})
+
+	unsafe := []struct {
+		name    string
+		entries []testEntry
+	}{
+		{"parent", []testEntry{{name: "../evil.txt", mode: 0o644}}},
+		{"absolute", []testEntry{{name: "/evil.txt", mode: 0o644}}},
+		{"drive letter", []testEntry{{name: "C:/evil.txt", mode: 0o644}}},
+		{"escaping symlink", []testEntry{{name: "a/link", mode: fs.ModeSymlink, content: "../../etc"}}},
+		{"absolute symlink", []testEntry{{name: "link", mode: fs.ModeSymlink, content: "/etc/passwd"}}},
+		{"chained symlink", []testEntry{
+			{name: "a/b/up", mode: fs.ModeSymlink, content: "../.."},
+			{name: "a/b/link", mode: fs.ModeSymlink, content: "up/.."},
+		}},
+	}
+	for _, tc := range unsafe {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			x := zip2jsons.Extractor{Dir: t.TempDir()}
+			err := x.Extract(newModeArchive(t, modified, tc.entries...))
+			if !errors.Is(err, zip2jsons.ErrUnsafeName) {
+				t.Errorf("Expected ErrUnsafeName, got %v", err)
+			}
+		})
+	}
+
+	t.Run("existing symlink", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		outside := t.TempDir()
+		err := os.Symlink(outside, filepath.Join(dir, "out"))
+		if err != nil {
+			t.Fatalf("Failed to create symlink: %v", err)
+		}
+
+		x := zip2jsons.Extractor{Dir: dir}
+		err = x.Extract(newModeArchive(t, modified, testEntry{name: "out/evil.txt", mode: 0o644}))
+		if !errors.Is(err, zip2jsons.ErrUnsafeName) {
+			t.Errorf("Expected ErrUnsafeName, got %v", err)
+		}
+		_, err = os.Stat(filepath.Join(outside, "evil.txt"))
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Expected nothing written outside, got %v", err)
+		}
+	})
+
+	t.Run("budgets", func(t *testing.T) {
+		t.Parallel()
+
+		arc := newModeArchive(t, modified, testEntry{name: "zeros", mode: 0o644, content: strings.Repeat("0", 4096)})
+		budgets := []zip2jsons.Extractor{
+			{MaxBytes: 1024},
+			{MaxTotal: 1024},
+			{MaxRatio: 10},
+		}
+		for _, x := range budgets {
+			x.Dir = t.TempDir()
+			err := x.Extract(arc)
+			if !errors.Is(err, zip2jsons.ErrBudgetExceeded) {
+				t.Errorf("Expected ErrBudgetExceeded for %+v, got %v", x, err)
+			}
+		}
+	})
+}
+
+func TestExtractor_Write(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	modified := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	bldr := bj.BlobBuilder{MaxBytes: 1024, LastModified: &modified}
+	blob, err := bldr.NewBlobFromReader(strings.NewReader("hello"), "a/b.txt")
+	if err != nil {
+		t.Fatalf("Failed to create blob: %v", err)
+	}
+
+	x := zip2jsons.Extractor{Dir: dir}
+	err = x.Write(blob)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "a", "b.txt"))
+	if err != nil || string(content) != "hello" {
+		t.Errorf("Expected hello, got %q (%v)", content, err)
+	}
+
+	blob.Name = "../b.txt"
+	err = x.Write(blob)
+	if !errors.Is(err, zip2jsons.ErrUnsafeName) {
+		t.Errorf("Expected ErrUnsafeName, got %v", err)
+	}
+}
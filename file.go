@@ -0,0 +1,32 @@
+package zip2jsons
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// OpenFileLike opens the local file as a FileLike.
+// The returned Closer must be closed once the archive is no longer used.
+func OpenFileLike(name string) (FileLike, io.Closer, error) {
+	f, e := os.Open(name) //nolint:gosec // the name is given by the user
+	if nil != e {
+		return FileLike{}, nil, fmt.Errorf("could not open %s: %w", name, e)
+	}
+
+	st, e := f.Stat()
+	if nil != e {
+		_ = f.Close()
+		return FileLike{}, nil, fmt.Errorf("could not stat %s: %w", name, e)
+	}
+
+	return FileLike{ReaderAt: f, Size: st.Size()}, f, nil
+}
+
+// ToZipLimited is like ToZip but rejects archives larger than the limit.
+func (l FileLike) ToZipLimited(limit int64) (ZipArchive, error) {
+	if limit < l.Size {
+		return ZipArchive{}, fmt.Errorf("%w: %d > %d", ErrArchiveTooLarge, l.Size, limit)
+	}
+	return l.ToZip()
+}
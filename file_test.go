@@ -0,0 +1,112 @@
+package zip2jsons_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+	"github.com/takanoriyanagitani/go-zip2blobs2jsons"
+)
+
+func TestOpenFileLike(t *testing.T) {
+	t.Parallel()
+
+	t.Run("existing zip", func(t *testing.T) {
+		t.Parallel()
+
+		f, closer, err := zip2jsons.OpenFileLike("./testdata.d/hw.zip")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer closer.Close() //nolint:errcheck
+
+		arc, err := f.ToZipLimited(1048576)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(arc.Files()) != 2 {
+			t.Errorf("Expected 2 files, got %d", len(arc.Files()))
+		}
+		if arc.Size != f.Size {
+			t.Errorf("Expected size %d, got %d", f.Size, arc.Size)
+		}
+
+		_, err = f.ToZipLimited(100)
+		if !errors.Is(err, zip2jsons.ErrArchiveTooLarge) {
+			t.Errorf("Expected ErrArchiveTooLarge, got %v", err)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		t.Parallel()
+
+		_, _, err := zip2jsons.OpenFileLike("./testdata.d/missing.zip")
+		if err == nil {
+			t.Errorf("Expected an error for a missing file")
+		}
+	})
+}
+
+func TestOpenMmapFileLike(t *testing.T) {
+	t.Parallel()
+
+	t.Run("existing zip", func(t *testing.T) {
+		t.Parallel()
+
+		f, closer, err := zip2jsons.OpenMmapFileLike("./testdata.d/hw.zip")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer closer.Close() //nolint:errcheck
+
+		arc, err := f.ToZipLimited(1048576)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(arc.Files()) != 2 {
+			t.Errorf("Expected 2 files, got %d", len(arc.Files()))
+		}
+
+		var sink zip2jsons.SliceSink
+		p := zip2jsons.Processor{Sink: &sink, Builder: bj.BlobBuilder{MaxBytes: 1024}}
+		err = p.Process(arc)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(sink.Blobs) != 2 || sink.Blobs[0].Body == "" {
+			t.Errorf("Unexpected blobs: %v", sink.Blobs)
+		}
+	})
+
+	t.Run("empty file", func(t *testing.T) {
+		t.Parallel()
+
+		name := filepath.Join(t.TempDir(), "empty.zip")
+		err := os.WriteFile(name, nil, 0o600)
+		if err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+
+		f, closer, err := zip2jsons.OpenMmapFileLike(name)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer closer.Close() //nolint:errcheck
+
+		_, err = f.ToZip()
+		if !errors.Is(err, zip2jsons.ErrNewReader) {
+			t.Errorf("Expected ErrNewReader, got %v", err)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		t.Parallel()
+
+		_, _, err := zip2jsons.OpenMmapFileLike("./testdata.d/missing.zip")
+		if err == nil {
+			t.Errorf("Expected an error for a missing file")
+		}
+	})
+}
@@ -0,0 +1,274 @@
+package zip2jsons
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+)
+
+// OutputFormat selects how the blobs are serialized.
+type OutputFormat string
+
+// Output formats.
+const (
+	// FormatNdjson writes one compact JSON document per line.
+	FormatNdjson OutputFormat = "ndjson"
+
+	// FormatPretty writes indented JSON documents.
+	FormatPretty OutputFormat = "pretty"
+
+	// FormatArray writes a single JSON array of the blobs.
+	FormatArray OutputFormat = "array"
+
+	// FormatObject writes a single JSON object keyed by the blob names.
+	FormatObject OutputFormat = "object"
+
+	// FormatCbor writes a CBOR sequence with raw binary bodies.
+	FormatCbor OutputFormat = "cbor"
+
+	// FormatMsgpack writes consecutive MessagePack maps with raw binary bodies.
+	FormatMsgpack OutputFormat = "msgpack"
+
+	// FormatAvro writes an Avro object container file.
+	FormatAvro OutputFormat = "avro"
+
+	// FormatParquet writes a Parquet file with one row per blob.
+	FormatParquet OutputFormat = "parquet"
+)
+
+// Extension returns the file name extension of the format.
+func (f OutputFormat) Extension() string {
+	switch f {
+	case FormatNdjson:
+		return ".ndjson"
+	case FormatCbor:
+		return ".cbor"
+	case FormatMsgpack:
+		return ".msgpack"
+	case FormatAvro:
+		return ".avro"
+	case FormatParquet:
+		return ".parquet"
+	default:
+		return ".json"
+	}
+}
+
+// ParseOutputFormat parses the name of an OutputFormat.
+func ParseOutputFormat(s string) (OutputFormat, error) {
+	switch f := OutputFormat(s); f {
+	case FormatNdjson, FormatPretty, FormatArray, FormatObject, FormatCbor, FormatMsgpack, FormatAvro,
+		FormatParquet:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown output format: %s", s)
+	}
+}
+
+// NewJsonEncoder creates a JsonEncoder with the HTML escaping of <, > and & enabled or disabled.
+func NewJsonEncoder(w io.Writer, escapeHTML bool) JsonEncoder {
+	var enc *json.Encoder = json.NewEncoder(w)
+	enc.SetEscapeHTML(escapeHTML)
+	return JsonEncoder{Encoder: enc, NoEscapeHTML: !escapeHTML}
+}
+
+// unescapeMetadataHTML re-encodes the metadata without the HTML escaping
+// which bj.BlobBuilder always applies.
+func unescapeMetadataHTML(b *bj.Blob) (*bj.Blob, error) {
+	if !bytes.Contains(b.Metadata, []byte(`\u00`)) {
+		return b, nil
+	}
+
+	var dec *json.Decoder = json.NewDecoder(bytes.NewReader(b.Metadata))
+	dec.UseNumber()
+	var meta any
+	e := dec.Decode(&meta)
+	if nil != e {
+		return nil, fmt.Errorf("could not decode metadata: %w", e)
+	}
+
+	var buf bytes.Buffer
+	var enc *json.Encoder = json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	e = enc.Encode(meta)
+	if nil != e {
+		return nil, fmt.Errorf("could not encode metadata: %w", e)
+	}
+
+	var unescaped bj.Blob = *b
+	unescaped.Metadata = bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+	return &unescaped, nil
+}
+
+// FormatOptions configures the sinks created by NewFormatSink.
+type FormatOptions struct {
+	// EscapeHTML escapes <, > and & in the JSON formats.
+	EscapeHTML bool
+
+	// AvroCodec is the block compression of FormatAvro.
+	AvroCodec AvroCodec
+
+	// ParquetRowGroupSize is the number of rows of the FormatParquet row groups.
+	ParquetRowGroupSize int
+
+	// ParquetRowGroupBytes is the number of value bytes of the FormatParquet row groups.
+	ParquetRowGroupBytes int64
+
+	// ParquetBody adds the body column to FormatParquet.
+	ParquetBody bool
+}
+
+// NewFormatSink creates a sink writing the blobs to w in the format.
+// The sink must be closed with CloseSink to complete the array, object, avro and parquet formats.
+func NewFormatSink(w io.Writer, format OutputFormat, opts FormatOptions) (BlobSink, error) {
+	var escapeHTML bool = opts.EscapeHTML
+	switch format {
+	case FormatNdjson:
+		return NewJsonStreamEncoder(w, escapeHTML), nil
+	case FormatPretty:
+		var enc JsonEncoder = NewJsonEncoder(w, escapeHTML)
+		enc.SetIndent("", "  ")
+		return enc, nil
+	case FormatArray:
+		return &JsonArrayEncoder{Writer: w, EscapeHTML: escapeHTML}, nil
+	case FormatObject:
+		return &JsonObjectEncoder{Writer: w, EscapeHTML: escapeHTML}, nil
+	case FormatCbor:
+		return &CborEncoder{Writer: w}, nil
+	case FormatMsgpack:
+		return &MsgpackEncoder{Writer: w}, nil
+	case FormatAvro:
+		return &AvroWriter{Writer: w, Codec: opts.AvroCodec}, nil
+	case FormatParquet:
+		return &ParquetWriter{
+			Writer:        w,
+			RowGroupSize:  opts.ParquetRowGroupSize,
+			RowGroupBytes: opts.ParquetRowGroupBytes,
+			Body:          opts.ParquetBody,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format: %s", format)
+	}
+}
+
+// jsonElements writes the blobs as the elements of a JSON array or object
+// as soon as they arrive.
+type jsonElements struct {
+	buf   bytes.Buffer
+	count int
+}
+
+func (j *jsonElements) encode(v any, escapeHTML bool) ([]byte, error) {
+	if b, ok := v.(*bj.Blob); ok && !escapeHTML {
+		var e error
+		v, e = unescapeMetadataHTML(b)
+		if nil != e {
+			return nil, e
+		}
+	}
+
+	j.buf.Reset()
+	var enc *json.Encoder = json.NewEncoder(&j.buf)
+	enc.SetEscapeHTML(escapeHTML)
+	e := enc.Encode(v)
+	if nil != e {
+		return nil, fmt.Errorf("could not encode blob: %w", e)
+	}
+	return bytes.TrimSuffix(j.buf.Bytes(), []byte("\n")), nil
+}
+
+func (j *jsonElements) separator(open string) string {
+	j.count++
+	if 1 == j.count {
+		return open + "\n"
+	}
+	return ",\n"
+}
+
+func (j *jsonElements) closing(open, end string) string {
+	if 0 == j.count {
+		return open + end + "\n"
+	}
+	return "\n" + end + "\n"
+}
+
+// JsonArrayEncoder writes the blobs as a single JSON array without buffering them.
+type JsonArrayEncoder struct {
+	io.Writer
+
+	EscapeHTML bool
+
+	elements jsonElements
+}
+
+// Write implements BlobSink.
+func (a *JsonArrayEncoder) Write(b *bj.Blob) error {
+	encoded, e := a.elements.encode(b, a.EscapeHTML)
+	if nil != e {
+		return e
+	}
+	_, e = io.WriteString(a.Writer, a.elements.separator("["))
+	if nil == e {
+		_, e = a.Writer.Write(encoded)
+	}
+	if nil != e {
+		return fmt.Errorf("could not write blob: %w", e)
+	}
+	return nil
+}
+
+// Close writes the end of the array. It does not close the underlying writer.
+func (a *JsonArrayEncoder) Close() error {
+	_, e := io.WriteString(a.Writer, a.elements.closing("[", "]"))
+	if nil != e {
+		return fmt.Errorf("could not close array: %w", e)
+	}
+	return nil
+}
+
+// JsonObjectEncoder writes the blobs as a single JSON object keyed by their names without buffering them.
+// Duplicate names result in duplicate keys.
+type JsonObjectEncoder struct {
+	io.Writer
+
+	EscapeHTML bool
+
+	elements jsonElements
+}
+
+// Write implements BlobSink.
+func (o *JsonObjectEncoder) Write(b *bj.Blob) error {
+	key, e := o.elements.encode(b.Name, o.EscapeHTML)
+	if nil != e {
+		return e
+	}
+	var prefix []byte = append(bytes.Clone(key), ':')
+
+	encoded, e := o.elements.encode(b, o.EscapeHTML)
+	if nil != e {
+		return e
+	}
+	_, e = io.WriteString(o.Writer, o.elements.separator("{"))
+	if nil == e {
+		_, e = o.Writer.Write(prefix)
+	}
+	if nil == e {
+		_, e = o.Writer.Write(encoded)
+	}
+	if nil != e {
+		return fmt.Errorf("could not write blob: %w", e)
+	}
+	return nil
+}
+
+// Close writes the end of the object. It does not close the underlying writer.
+func (o *JsonObjectEncoder) Close() error {
+	_, e := io.WriteString(o.Writer, o.elements.closing("{", "}"))
+	if nil != e {
+		return fmt.Errorf("could not close object: %w", e)
+	}
+	return nil
+}
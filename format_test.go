@@ -0,0 +1,113 @@
+package zip2jsons_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+	"github.com/takanoriyanagitani/go-zip2blobs2jsons"
+)
+
+func formatOutput(t *testing.T, format zip2jsons.OutputFormat, escapeHTML bool, pairs ...string) string {
+	t.Helper()
+
+	buf := new(bytes.Buffer)
+	sink, err := zip2jsons.NewFormatSink(buf, format, zip2jsons.FormatOptions{EscapeHTML: escapeHTML})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var bldr bj.BlobBuilder = bj.BlobBuilder{
+		MaxBytes: 1024,
+		Metadata: map[string]string{"tag": "<a&b>"},
+	}
+	err = zip2jsons.ProcessZipArchive(newTestArchive(t, pairs...), sink, bldr)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err = zip2jsons.CloseSink(sink)
+	if err != nil {
+		t.Fatalf("Failed to close sink: %v", err)
+	}
+	return buf.String()
+}
+
+func TestNewFormatSink(t *testing.T) {
+	t.Parallel()
+
+	t.Run("array", func(t *testing.T) {
+		t.Parallel()
+
+		out := formatOutput(t, zip2jsons.FormatArray, true, "a.txt", "1", "b.txt", "2")
+		var blobs []bj.Blob
+		err := json.Unmarshal([]byte(out), &blobs)
+		if err != nil {
+			t.Fatalf("Invalid JSON array %s: %v", out, err)
+		}
+		if len(blobs) != 2 || blobs[1].Name != "b.txt" {
+			t.Errorf("Unexpected blobs: %+v", blobs)
+		}
+	})
+
+	t.Run("empty array", func(t *testing.T) {
+		t.Parallel()
+
+		out := formatOutput(t, zip2jsons.FormatArray, true)
+		if out != "[]\n" {
+			t.Errorf("Expected empty array, got %q", out)
+		}
+	})
+
+	t.Run("object", func(t *testing.T) {
+		t.Parallel()
+
+		out := formatOutput(t, zip2jsons.FormatObject, true, "a.txt", "1", "d/b.txt", "2")
+		var blobs map[string]bj.Blob
+		err := json.Unmarshal([]byte(out), &blobs)
+		if err != nil {
+			t.Fatalf("Invalid JSON object %s: %v", out, err)
+		}
+		if blobs["d/b.txt"].Body != "Mg==" || len(blobs) != 2 {
+			t.Errorf("Unexpected blobs: %+v", blobs)
+		}
+	})
+
+	t.Run("pretty", func(t *testing.T) {
+		t.Parallel()
+
+		out := formatOutput(t, zip2jsons.FormatPretty, true, "a.txt", "1")
+		if !strings.Contains(out, "\n  \"name\": \"a.txt\",\n") {
+			t.Errorf("Expected indented output, got %s", out)
+		}
+	})
+
+	t.Run("html escaping", func(t *testing.T) {
+		t.Parallel()
+
+		escaped := formatOutput(t, zip2jsons.FormatNdjson, true, "<x>.txt", "1")
+		if strings.Contains(escaped, "<") {
+			t.Errorf("Expected escaped output, got %s", escaped)
+		}
+
+		for _, format := range []zip2jsons.OutputFormat{zip2jsons.FormatNdjson, zip2jsons.FormatArray} {
+			raw := formatOutput(t, format, false, "<x>.txt", "1")
+			if !strings.Contains(raw, `"name":"<x>.txt"`) || !strings.Contains(raw, `"tag":"<a&b>"`) {
+				t.Errorf("Expected unescaped %s output, got %s", format, raw)
+			}
+		}
+	})
+}
+
+func TestParseOutputFormat(t *testing.T) {
+	t.Parallel()
+
+	_, err := zip2jsons.ParseOutputFormat("yaml")
+	if err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+	format, err := zip2jsons.ParseOutputFormat("array")
+	if err != nil || format != zip2jsons.FormatArray {
+		t.Errorf("Unexpected result: %v, %v", format, err)
+	}
+}
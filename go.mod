@@ -2,4 +2,9 @@ module github.com/takanoriyanagitani/go-zip2blobs2jsons
 
 go 1.25.5
 
-require github.com/takanoriyanagitani/go-blob2json v0.0.0-20251215230720-f2bf64116f9a // indirect
+require (
+	github.com/klauspost/compress v1.18.0
+	github.com/takanoriyanagitani/go-blob2json v0.0.0-20251215230720-f2bf64116f9a
+	github.com/ulikunitz/xz v0.5.15
+	golang.org/x/text v0.40.0
+)
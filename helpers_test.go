@@ -0,0 +1,43 @@
+package zip2jsons_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"math/rand/v2"
+	"testing"
+)
+
+// newZipBytes creates a zip with a large stored item followed by the small items.
+func newZipBytes(t *testing.T, large int, pairs ...string) []byte {
+	t.Helper()
+
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	f, err := w.CreateHeader(&zip.FileHeader{Name: "large.bin", Method: zip.Store})
+	if err != nil {
+		t.Fatalf("Failed to create large.bin: %v", err)
+	}
+	data := make([]byte, large)
+	for i := range data {
+		data[i] = byte(rand.IntN(256))
+	}
+	_, err = f.Write(data)
+	if err != nil {
+		t.Fatalf("Failed to write large.bin: %v", err)
+	}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		f, err := w.Create(pairs[i])
+		if err != nil {
+			t.Fatalf("Failed to create %s: %v", pairs[i], err)
+		}
+		_, err = f.Write([]byte(pairs[i+1]))
+		if err != nil {
+			t.Fatalf("Failed to write %s: %v", pairs[i], err)
+		}
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("Failed to close zip writer: %v", err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,289 @@
+//go:build !tinygo
+
+package zip2jsons
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Defaults of the HTTPReaderAt.
+const (
+	DefaultHTTPBlockSize   = 256 * 1024
+	DefaultHTTPCacheBlocks = 64
+	DefaultHTTPReadAhead   = 3
+)
+
+// HTTPReaderAt reads a remote file with HTTP range requests.
+//
+// The file is read in blocks which are kept in an LRU cache.
+// A missed block is fetched together with the following ReadAhead blocks in a single request.
+// It is safe for concurrent use once opened.
+type HTTPReaderAt struct {
+	// Client sends the requests. Defaults to http.DefaultClient.
+	Client *http.Client
+
+	URL string
+
+	// Context is used for the requests. Defaults to context.Background.
+	Context context.Context
+
+	// BlockSize is the size of the cached blocks. Defaults to DefaultHTTPBlockSize.
+	BlockSize int64
+
+	// CacheBlocks is the number of cached blocks. Defaults to DefaultHTTPCacheBlocks.
+	CacheBlocks int
+
+	// ReadAhead is the number of blocks fetched after a missed block. Defaults to DefaultHTTPReadAhead.
+	// Negative values disable the read-ahead.
+	ReadAhead int
+
+	size int64
+
+	mu     sync.Mutex
+	lru    *list.List
+	blocks map[int64]*list.Element
+}
+
+type httpBlock struct {
+	index int64
+	data  []byte
+}
+
+func (h *HTTPReaderAt) client() *http.Client {
+	if nil != h.Client {
+		return h.Client
+	}
+	return http.DefaultClient
+}
+
+func (h *HTTPReaderAt) context() context.Context {
+	if nil != h.Context {
+		return h.Context
+	}
+	return context.Background()
+}
+
+func (h *HTTPReaderAt) blockSize() int64 {
+	if 0 < h.BlockSize {
+		return h.BlockSize
+	}
+	return DefaultHTTPBlockSize
+}
+
+func (h *HTTPReaderAt) cacheBlocks() int {
+	if 0 < h.CacheBlocks {
+		return h.CacheBlocks
+	}
+	return DefaultHTTPCacheBlocks
+}
+
+func (h *HTTPReaderAt) readAhead() int64 {
+	switch {
+	case h.ReadAhead < 0:
+		return 0
+	case 0 == h.ReadAhead:
+		return DefaultHTTPReadAhead
+	default:
+		return int64(h.ReadAhead)
+	}
+}
+
+func (h *HTTPReaderAt) do(method string, header map[string]string) (*http.Response, error) {
+	req, e := http.NewRequestWithContext(h.context(), method, h.URL, nil)
+	if nil != e {
+		return nil, fmt.Errorf("could not create request for %s: %w", h.URL, e)
+	}
+	for key, val := range header {
+		req.Header.Set(key, val)
+	}
+
+	res, e := h.client().Do(req)
+	if nil != e {
+		return nil, fmt.Errorf("could not request %s: %w", h.URL, e)
+	}
+	return res, nil
+}
+
+// parseContentRange parses the "bytes first-last/size" value of Content-Range.
+func parseContentRange(value string) (first, last, size int64, e error) {
+	rng, found := strings.CutPrefix(value, "bytes ")
+	span, total, ok := strings.Cut(rng, "/")
+	start, end, ok2 := strings.Cut(span, "-")
+	if !found || !ok || !ok2 {
+		return 0, 0, 0, fmt.Errorf("%w: invalid Content-Range %q", ErrRangeRequest, value)
+	}
+
+	first, e = strconv.ParseInt(start, 10, 64)
+	if nil == e {
+		last, e = strconv.ParseInt(end, 10, 64)
+	}
+	if nil == e {
+		size, e = strconv.ParseInt(total, 10, 64)
+	}
+	if nil != e {
+		return 0, 0, 0, fmt.Errorf("%w: invalid Content-Range %q", ErrRangeRequest, value)
+	}
+	return first, last, size, nil
+}
+
+// probeSize asks for the first byte of the file, which also checks that ranges are supported.
+func (h *HTTPReaderAt) probeSize() (int64, error) {
+	res, e := h.do(http.MethodGet, map[string]string{"Range": "bytes=0-0"})
+	if nil != e {
+		return 0, e
+	}
+	defer res.Body.Close() //nolint:errcheck // only the header is used
+
+	if http.StatusPartialContent != res.StatusCode {
+		return 0, fmt.Errorf("%w: %s answered %s", ErrRangeRequest, h.URL, res.Status)
+	}
+	_, _, size, e := parseContentRange(res.Header.Get("Content-Range"))
+	return size, e
+}
+
+// Open determines the size of the remote file and returns it as a FileLike.
+// The size is taken from the Content-Length of a HEAD request
+// if the server advertises byte ranges, and from a single byte range request otherwise.
+func (h *HTTPReaderAt) Open() (FileLike, error) {
+	res, e := h.do(http.MethodHead, nil)
+	if nil != e {
+		return FileLike{}, e
+	}
+	_ = res.Body.Close()
+
+	switch {
+	case http.StatusOK == res.StatusCode && 0 <= res.ContentLength && "bytes" == res.Header.Get("Accept-Ranges"):
+		h.size = res.ContentLength
+	default:
+		h.size, e = h.probeSize()
+		if nil != e {
+			return FileLike{}, e
+		}
+	}
+
+	h.lru = list.New()
+	h.blocks = map[int64]*list.Element{}
+	return FileLike{ReaderAt: h, Size: h.size}, nil
+}
+
+// cached returns the block if it is in the cache.
+func (h *HTTPReaderAt) cached(index int64) ([]byte, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	elem, ok := h.blocks[index]
+	if !ok {
+		return nil, false
+	}
+	h.lru.MoveToFront(elem)
+	return elem.Value.(httpBlock).data, true //nolint:forcetypeassert // only blocks are stored
+}
+
+func (h *HTTPReaderAt) store(index int64, data []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if elem, ok := h.blocks[index]; ok {
+		h.lru.MoveToFront(elem)
+		return
+	}
+	h.blocks[index] = h.lru.PushFront(httpBlock{index: index, data: data})
+	for h.cacheBlocks() < h.lru.Len() {
+		var oldest *list.Element = h.lru.Back()
+		h.lru.Remove(oldest)
+		delete(h.blocks, oldest.Value.(httpBlock).index) //nolint:forcetypeassert // only blocks are stored
+	}
+}
+
+// fetch requests the block and the read-ahead blocks which are not cached yet.
+func (h *HTTPReaderAt) fetch(index int64) ([]byte, error) {
+	var bsize int64 = h.blockSize()
+	var blocks int64 = (h.size + bsize - 1) / bsize
+	var last int64 = index
+	for last+1 < blocks && last-index < h.readAhead() {
+		if _, ok := h.cached(last + 1); ok {
+			break
+		}
+		last++
+	}
+
+	var first int64 = index * bsize
+	var end int64 = min((last+1)*bsize, h.size)
+	res, e := h.do(http.MethodGet, map[string]string{
+		"Range": fmt.Sprintf("bytes=%d-%d", first, end-1),
+	})
+	if nil != e {
+		return nil, e
+	}
+	defer res.Body.Close() //nolint:errcheck // the body is read fully
+
+	if http.StatusPartialContent != res.StatusCode {
+		return nil, fmt.Errorf("%w: %s answered %s", ErrRangeRequest, h.URL, res.Status)
+	}
+	start, _, _, e := parseContentRange(res.Header.Get("Content-Range"))
+	if nil != e {
+		return nil, e
+	}
+	if first != start {
+		return nil, fmt.Errorf("%w: expected offset %d, got %d", ErrRangeRequest, first, start)
+	}
+
+	var data []byte = make([]byte, end-first)
+	_, e = io.ReadFull(res.Body, data)
+	if nil != e {
+		return nil, fmt.Errorf("could not read %s: %w", h.URL, e)
+	}
+
+	if index == last {
+		h.store(index, data)
+		return data, nil
+	}
+	// each block in its own array, so that evicting it frees its memory
+	var head []byte
+	for i := index; i <= last; i++ {
+		var off int64 = (i - index) * bsize
+		var block []byte = bytes.Clone(data[off:min(off+bsize, int64(len(data)))])
+		h.store(i, block)
+		if index == i {
+			head = block
+		}
+	}
+	return head, nil
+}
+
+// ReadAt implements io.ReaderAt.
+func (h *HTTPReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("%w: negative offset %d", ErrRangeRequest, off)
+	}
+
+	var bsize int64 = h.blockSize()
+	var n int
+	for n < len(p) && off+int64(n) < h.size {
+		var pos int64 = off + int64(n)
+		var index int64 = pos / bsize
+
+		data, ok := h.cached(index)
+		if !ok {
+			var e error
+			data, e = h.fetch(index)
+			if nil != e {
+				return n, e
+			}
+		}
+		n += copy(p[n:], data[pos-index*bsize:])
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
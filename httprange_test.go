@@ -0,0 +1,156 @@
+//go:build !tinygo
+
+package zip2jsons_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/takanoriyanagitani/go-zip2blobs2jsons"
+)
+
+type countingServer struct {
+	*httptest.Server
+
+	requests atomic.Int64
+	bytes    atomic.Int64
+}
+
+type countingWriter struct {
+	http.ResponseWriter
+
+	bytes *atomic.Int64
+}
+
+func (c countingWriter) Write(p []byte) (int, error) {
+	c.bytes.Add(int64(len(p)))
+	return c.ResponseWriter.Write(p)
+}
+
+func newCountingServer(t *testing.T, content []byte, handler func(http.ResponseWriter, *http.Request) bool) *countingServer {
+	t.Helper()
+
+	s := &countingServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		if nil != handler && handler(w, r) {
+			return
+		}
+		http.ServeContent(countingWriter{ResponseWriter: w, bytes: &s.bytes}, r, "a.zip", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestHTTPReaderAt(t *testing.T) {
+	t.Parallel()
+
+	content := newZipBytes(t, 1<<20, "a.txt", "hello", "b.txt", "world")
+
+	t.Run("reads the items", func(t *testing.T) {
+		t.Parallel()
+
+		s := newCountingServer(t, content, nil)
+		h := &zip2jsons.HTTPReaderAt{URL: s.URL, BlockSize: 4096}
+		f, err := h.Open()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if f.Size != int64(len(content)) {
+			t.Errorf("Expected size %d, got %d", len(content), f.Size)
+		}
+
+		arc, err := f.ToZip()
+		if err != nil {
+			t.Fatalf("Failed to read zip: %v", err)
+		}
+		for _, zfile := range arc.Files()[1:] {
+			rc, err := zfile.Open()
+			if err != nil {
+				t.Fatalf("Failed to open %s: %v", zfile.Name, err)
+			}
+			body, err := io.ReadAll(rc)
+			_ = rc.Close()
+			if err != nil {
+				t.Fatalf("Failed to read %s: %v", zfile.Name, err)
+			}
+			if string(body) != map[string]string{"a.txt": "hello", "b.txt": "world"}[zfile.Name] {
+				t.Errorf("Unexpected body of %s: %s", zfile.Name, body)
+			}
+		}
+
+		if served := s.bytes.Load(); 64*1024 < served {
+			t.Errorf("Expected only the tail to be downloaded, got %d bytes", served)
+		}
+
+		requests := s.requests.Load()
+		buf := make([]byte, 100)
+		_, err = h.ReadAt(buf, f.Size-100)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if s.requests.Load() != requests {
+			t.Errorf("Expected the tail to be cached")
+		}
+	})
+
+	t.Run("read past the end", func(t *testing.T) {
+		t.Parallel()
+
+		s := newCountingServer(t, content, nil)
+		h := &zip2jsons.HTTPReaderAt{URL: s.URL, BlockSize: 1000, ReadAhead: -1}
+		f, err := h.Open()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		buf := make([]byte, 3000)
+		n, err := h.ReadAt(buf, f.Size-1500)
+		if n != 1500 || !errors.Is(err, io.EOF) {
+			t.Errorf("Expected 1500 bytes and EOF, got %d and %v", n, err)
+		}
+		if !bytes.Equal(buf[:n], content[len(content)-1500:]) {
+			t.Errorf("Unexpected content")
+		}
+	})
+
+	t.Run("size without HEAD", func(t *testing.T) {
+		t.Parallel()
+
+		s := newCountingServer(t, content, func(w http.ResponseWriter, r *http.Request) bool {
+			if http.MethodHead != r.Method {
+				return false
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return true
+		})
+		h := &zip2jsons.HTTPReaderAt{URL: s.URL}
+		f, err := h.Open()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if f.Size != int64(len(content)) {
+			t.Errorf("Expected size %d, got %d", len(content), f.Size)
+		}
+	})
+
+	t.Run("ranges unsupported", func(t *testing.T) {
+		t.Parallel()
+
+		s := newCountingServer(t, content, func(w http.ResponseWriter, _ *http.Request) bool {
+			_, _ = w.Write(content)
+			return true
+		})
+		h := &zip2jsons.HTTPReaderAt{URL: s.URL}
+		_, err := h.Open()
+		if !errors.Is(err, zip2jsons.ErrRangeRequest) {
+			t.Errorf("Expected ErrRangeRequest, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package zip2jsons
+
+import (
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ItemInfo describes an item and its position within the archive.
+type ItemInfo struct {
+	// Index is the zero-based position in the central directory.
+	Index int
+
+	// Total is the number of entries in the central directory.
+	Total int
+
+	Name string
+}
+
+func (i ItemInfo) trimmed() string { return strings.TrimSuffix(i.Name, "/") }
+
+// Dir returns the directory part of the name. It is empty for top-level items.
+func (i ItemInfo) Dir() string {
+	dir, _ := path.Split(i.trimmed())
+	return strings.TrimSuffix(dir, "/")
+}
+
+// Base returns the last element of the name.
+func (i ItemInfo) Base() string {
+	_, base := path.Split(i.trimmed())
+	return base
+}
+
+// Ext returns the extension of the base name including the dot.
+func (i ItemInfo) Ext() string { return path.Ext(i.Base()) }
+
+// Depth returns the number of directories containing the item.
+func (i ItemInfo) Depth() int { return strings.Count(i.trimmed(), "/") }
+
+// Expand replaces the placeholders {{index}}, {{total}}, {{name}}, {{dir}}, {{base}}, {{ext}}
+// and {{depth}} in the template with the fields of the item.
+func (i ItemInfo) Expand(template string) string {
+	if !strings.Contains(template, "{{") {
+		return template
+	}
+	return strings.NewReplacer(
+		"{{index}}", strconv.Itoa(i.Index),
+		"{{total}}", strconv.Itoa(i.Total),
+		"{{name}}", i.Name,
+		"{{dir}}", i.Dir(),
+		"{{base}}", i.Base(),
+		"{{ext}}", i.Ext(),
+		"{{depth}}", strconv.Itoa(i.Depth()),
+	).Replace(template)
+}
+
+// ExpandMetadata returns a copy of the metadata with the placeholders in its values expanded.
+// The MetaZipName value is a file name, not a template, and is copied as is.
+func (i ItemInfo) ExpandMetadata(meta map[string]string) map[string]string {
+	var expanded map[string]string = make(map[string]string, len(meta))
+	for key, val := range meta {
+		if MetaZipName == key {
+			expanded[key] = val
+			continue
+		}
+		expanded[key] = i.Expand(val)
+	}
+	return expanded
+}
+
+// Metadata keys of the fields derived from the item.
+const (
+	MetaIndex = "index"
+	MetaTotal = "total"
+	MetaDir   = "dir"
+	MetaBase  = "base"
+	MetaExt   = "ext"
+	MetaDepth = "depth"
+)
+
+// Derived returns the fields derived from the name and the position as metadata.
+func (i ItemInfo) Derived() map[string]string {
+	return map[string]string{
+		MetaIndex: strconv.Itoa(i.Index),
+		MetaTotal: strconv.Itoa(i.Total),
+		MetaDir:   i.Dir(),
+		MetaBase:  i.Base(),
+		MetaExt:   i.Ext(),
+		MetaDepth: strconv.Itoa(i.Depth()),
+	}
+}
+
+func hasTemplate(meta map[string]string) bool {
+	for key, val := range meta {
+		if MetaZipName != key && strings.Contains(val, "{{") {
+			return true
+		}
+	}
+	return false
+}
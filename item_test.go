@@ -0,0 +1,125 @@
+package zip2jsons_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+	"github.com/takanoriyanagitani/go-zip2blobs2jsons"
+)
+
+func TestItemInfo(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		dir   string
+		base  string
+		ext   string
+		depth int
+	}{
+		{name: "a.txt", dir: "", base: "a.txt", ext: ".txt", depth: 0},
+		{name: "x/y/b.tar.gz", dir: "x/y", base: "b.tar.gz", ext: ".gz", depth: 2},
+		{name: "x/y/", dir: "x", base: "y", ext: "", depth: 1},
+		{name: "noext", dir: "", base: "noext", ext: "", depth: 0},
+	}
+
+	for _, tc := range tests {
+		var info zip2jsons.ItemInfo = zip2jsons.ItemInfo{Name: tc.name}
+		if info.Dir() != tc.dir {
+			t.Errorf("%s: expected dir '%s', got '%s'", tc.name, tc.dir, info.Dir())
+		}
+		if info.Base() != tc.base {
+			t.Errorf("%s: expected base '%s', got '%s'", tc.name, tc.base, info.Base())
+		}
+		if info.Ext() != tc.ext {
+			t.Errorf("%s: expected ext '%s', got '%s'", tc.name, tc.ext, info.Ext())
+		}
+		if info.Depth() != tc.depth {
+			t.Errorf("%s: expected depth %d, got %d", tc.name, tc.depth, info.Depth())
+		}
+	}
+}
+
+func TestItemInfo_Expand(t *testing.T) {
+	t.Parallel()
+
+	var info zip2jsons.ItemInfo = zip2jsons.ItemInfo{Index: 2, Total: 5, Name: "d/f.csv"}
+	got := info.Expand("{{index}}/{{total}} {{dir}} {{base}} {{ext}} {{depth}} {{unknown}}")
+	expected := "2/5 d f.csv .csv 1 {{unknown}}"
+	if got != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, got)
+	}
+}
+
+func TestProcessor_MetadataTemplates(t *testing.T) {
+	t.Parallel()
+
+	var sink zip2jsons.SliceSink
+	var proc zip2jsons.Processor = zip2jsons.Processor{
+		Sink: &sink,
+		Builder: bj.BlobBuilder{
+			MaxBytes: 1024,
+			Metadata: map[string]string{
+				"batch":               "b1",
+				"pos":                 "{{index}}{{ext}}",
+				zip2jsons.MetaZipName: "{{name}}.zip",
+			},
+		},
+	}
+	err := proc.Process(newTestArchive(t, "a.txt", "1", "b.json", "2"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for i, expected := range []string{"0.txt", "1.json"} {
+		var meta map[string]string
+		err = json.Unmarshal(sink.Blobs[i].Metadata, &meta)
+		if err != nil {
+			t.Fatalf("Failed to decode metadata: %v", err)
+		}
+		if meta["pos"] != expected || meta["batch"] != "b1" || meta[zip2jsons.MetaZipName] != "{{name}}.zip" {
+			t.Errorf("Unexpected metadata: %v", meta)
+		}
+	}
+	if proc.Builder.Metadata["pos"] != "{{index}}{{ext}}" {
+		t.Errorf("Builder metadata modified: %v", proc.Builder.Metadata)
+	}
+}
+
+func TestProcessor_DerivedMetadata(t *testing.T) {
+	t.Parallel()
+
+	var sink zip2jsons.SliceSink
+	var proc zip2jsons.Processor = zip2jsons.Processor{
+		Sink: &sink,
+		Builder: bj.BlobBuilder{
+			MaxBytes: 1024,
+			Metadata: map[string]string{"ext": "override"},
+		},
+		DerivedMetadata: true,
+	}
+	err := proc.Process(newTestArchive(t, "a.txt", "1", "d/e/b.json", "2"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var meta map[string]string
+	err = json.Unmarshal(sink.Blobs[1].Metadata, &meta)
+	if err != nil {
+		t.Fatalf("Failed to decode metadata: %v", err)
+	}
+	expected := map[string]string{
+		"index": "1",
+		"total": "2",
+		"dir":   "d/e",
+		"base":  "b.json",
+		"ext":   "override",
+		"depth": "2",
+	}
+	for key, val := range expected {
+		if meta[key] != val {
+			t.Errorf("Expected %s=%s, got %s", key, val, meta[key])
+		}
+	}
+}
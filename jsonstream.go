@@ -0,0 +1,353 @@
+package zip2jsons
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+)
+
+// base64Chunk is the size of the raw content encoded at once; a multiple of 3.
+const base64Chunk = 3 * 1024
+
+// streamBufferDefault is the default of JsonStreamEncoder.StreamBuffer,
+// enough for the records of items up to a few KB.
+const streamBufferDefault = 8 << 10
+
+// MetaStreamError is the metadata key of a streamed record whose content could not be read to its end,
+// holding the error. The body of such a record is cut after the content read before the error.
+const MetaStreamError = "stream_error"
+
+// jsonHead holds the fields of a blob written before the body.
+type jsonHead struct {
+	Name                    string `json:"name"`
+	ContentType             string `json:"content_type"`
+	ContentEncoding         string `json:"content_encoding"`
+	ContentTransferEncoding string `json:"content_transfer_encoding"`
+}
+
+// jsonTail holds the fields of a blob written after the body.
+type jsonTail struct {
+	Metadata      json.RawMessage `json:"metadata,omitempty"`
+	ContentLength *int64          `json:"content_length,omitempty"`
+	LastModified  *time.Time      `json:"last_modified,omitempty"`
+}
+
+// JsonStreamEncoder writes the blobs as NDJSON like JsonEncoder,
+// but encodes the raw content of a RawBlobSink or the content read by a StreamBlobSink
+// straight into the writer instead of building the base64 body string first.
+type JsonStreamEncoder struct {
+	io.Writer
+
+	EscapeHTML bool
+
+	// StreamBuffer is the number of bytes of a record written by WriteStream which are held back
+	// until the record is complete, so that a record failing within them is dropped. Defaults to 8 KiB.
+	// The held bytes are released after the records needing more than the default.
+	StreamBuffer int
+
+	hold    holdWriter
+	buf     bytes.Buffer
+	enc     *json.Encoder
+	scratch []byte
+	chunk   []byte
+}
+
+// NewJsonStreamEncoder creates a JsonStreamEncoder.
+func NewJsonStreamEncoder(w io.Writer, escapeHTML bool) *JsonStreamEncoder {
+	return &JsonStreamEncoder{Writer: w, EscapeHTML: escapeHTML}
+}
+
+// encode encodes the value into the scratch buffer.
+func (s *JsonStreamEncoder) encode(v any) ([]byte, error) {
+	if nil == s.enc {
+		s.enc = json.NewEncoder(&s.buf)
+		s.enc.SetEscapeHTML(s.EscapeHTML)
+	}
+	s.buf.Reset()
+	e := s.enc.Encode(v)
+	if nil != e {
+		return nil, fmt.Errorf("could not encode blob: %w", e)
+	}
+	return s.buf.Bytes(), nil
+}
+
+// writeHead writes the blob up to the opening quote of its body.
+func (s *JsonStreamEncoder) writeHead(w io.Writer, b *bj.Blob) error {
+	head, e := s.encode(jsonHead{
+		Name:                    b.Name,
+		ContentType:             b.ContentType,
+		ContentEncoding:         b.ContentEncoding,
+		ContentTransferEncoding: b.ContentTransferEncoding,
+	})
+	if nil != e {
+		return e
+	}
+
+	// drop the closing "}\n" to continue the object
+	_, e = w.Write(head[:len(head)-2])
+	if nil == e {
+		_, e = io.WriteString(w, `,"body":"`)
+	}
+	return e
+}
+
+// writeTail writes the blob from the closing quote of its body.
+func (s *JsonStreamEncoder) writeTail(w io.Writer, b *bj.Blob) error {
+	if !s.EscapeHTML {
+		var e error
+		b, e = unescapeMetadataHTML(b)
+		if nil != e {
+			return e
+		}
+	}
+
+	tail, e := s.encode(jsonTail{
+		Metadata:      b.Metadata,
+		ContentLength: b.ContentLength,
+		LastModified:  b.LastModified,
+	})
+	if nil != e {
+		return e
+	}
+
+	// replace the opening "{" of the tail with the separator of the fields
+	if "{}\n" == string(tail) {
+		_, e = io.WriteString(w, "\"}\n")
+		return e
+	}
+	_, e = io.WriteString(w, "\",")
+	if nil == e {
+		_, e = w.Write(tail[1:])
+	}
+	return e
+}
+
+// writeBase64Stream encodes the content read in chunks of a multiple of 3 bytes,
+// so that only the last chunk is padded.
+// It returns the number of bytes of the content written, even on error.
+func (s *JsonStreamEncoder) writeBase64Stream(w io.Writer, content io.Reader) (int64, error) {
+	if nil == s.chunk {
+		s.chunk = make([]byte, base64Chunk)
+	}
+	var total int64
+	for {
+		n, e := io.ReadFull(content, s.chunk)
+		var done bool = errors.Is(e, io.EOF) || errors.Is(e, io.ErrUnexpectedEOF)
+		if nil != e && !done {
+			return total, e
+		}
+
+		e = s.writeBase64(w, s.chunk[:n])
+		if nil != e {
+			return total, e
+		}
+		total += int64(n)
+		if done {
+			return total, nil
+		}
+	}
+}
+
+// writeBase64 encodes the content in chunks through the scratch buffer.
+func (s *JsonStreamEncoder) writeBase64(w io.Writer, content []byte) error {
+	if nil == s.scratch {
+		s.scratch = make([]byte, base64.StdEncoding.EncodedLen(base64Chunk))
+	}
+	for 0 < len(content) {
+		var chunk []byte = content[:min(base64Chunk, len(content))]
+		content = content[len(chunk):]
+
+		var n int = base64.StdEncoding.EncodedLen(len(chunk))
+		base64.StdEncoding.Encode(s.scratch[:n], chunk)
+		_, e := w.Write(s.scratch[:n])
+		if nil != e {
+			return e
+		}
+	}
+	return nil
+}
+
+// plainJSON reports whether the string needs no escaping in JSON, as a base64 body.
+func plainJSON(s string) bool {
+	for i := range len(s) {
+		var c byte = s[i]
+		if c < 0x20 || 0x80 <= c || '"' == c || '\\' == c || '<' == c || '>' == c || '&' == c {
+			return false
+		}
+	}
+	return true
+}
+
+// Write implements BlobSink.
+func (s *JsonStreamEncoder) Write(b *bj.Blob) error {
+	if !plainJSON(b.Body) {
+		return s.writeEscaped(b)
+	}
+
+	e := s.writeHead(s.Writer, b)
+	if nil == e {
+		// the base64 alphabet needs no escaping
+		_, e = io.WriteString(s.Writer, b.Body)
+	}
+	if nil == e {
+		e = s.writeTail(s.Writer, b)
+	}
+	if nil != e {
+		return fmt.Errorf("could not write blob %s: %w", b.Name, e)
+	}
+	return nil
+}
+
+// WriteRaw implements RawBlobSink.
+func (s *JsonStreamEncoder) WriteRaw(b *bj.Blob, content []byte) error {
+	e := s.writeHead(s.Writer, b)
+	if nil == e {
+		e = s.writeBase64(s.Writer, content)
+	}
+	if nil == e {
+		e = s.writeTail(s.Writer, b)
+	}
+	if nil != e {
+		return fmt.Errorf("could not write blob %s: %w", b.Name, e)
+	}
+	return nil
+}
+
+// writeEscaped writes a blob whose body is not plain base64 like JsonEncoder does.
+func (s *JsonStreamEncoder) writeEscaped(b *bj.Blob) error {
+	if !s.EscapeHTML {
+		var e error
+		b, e = unescapeMetadataHTML(b)
+		if nil != e {
+			return e
+		}
+	}
+	encoded, e := s.encode(b)
+	if nil == e {
+		_, e = s.Writer.Write(encoded)
+	}
+	if nil != e {
+		return fmt.Errorf("could not write blob %s: %w", b.Name, e)
+	}
+	return nil
+}
+
+// holdWriter holds the writes back up to the limit, so that they can still be dropped.
+type holdWriter struct {
+	io.Writer
+
+	held    bytes.Buffer
+	limit   int
+	flushed bool
+}
+
+// reset starts holding the writes to w back.
+// The held buffer is released if it grew beyond the default size.
+func (h *holdWriter) reset(w io.Writer, limit int) {
+	h.Writer = w
+	if streamBufferDefault < h.held.Cap() {
+		h.held = bytes.Buffer{}
+	}
+	h.held.Reset()
+	h.limit = limit
+	h.flushed = false
+}
+
+func (h *holdWriter) Write(p []byte) (int, error) {
+	if !h.flushed && h.held.Len()+len(p) <= h.limit {
+		return h.held.Write(p)
+	}
+	e := h.flush()
+	if nil != e {
+		return 0, e
+	}
+	return h.Writer.Write(p)
+}
+
+// flush writes the held data through.
+func (h *holdWriter) flush() error {
+	if h.flushed {
+		return nil
+	}
+	h.flushed = true
+	_, e := h.Writer.Write(h.held.Bytes())
+	h.held.Reset()
+	return e
+}
+
+// withMetadata returns a copy of the blob with the string value added to its metadata.
+func withMetadata(b *bj.Blob, key, value string) (*bj.Blob, error) {
+	var meta map[string]json.RawMessage
+	if 0 < len(b.Metadata) {
+		e := json.Unmarshal(b.Metadata, &meta)
+		if nil != e {
+			return nil, fmt.Errorf("could not decode metadata: %w", e)
+		}
+	}
+	if nil == meta {
+		meta = map[string]json.RawMessage{}
+	}
+	val, e := json.Marshal(value)
+	if nil != e {
+		return nil, fmt.Errorf("could not encode metadata: %w", e)
+	}
+	meta[key] = val
+
+	raw, e := json.Marshal(meta)
+	if nil != e {
+		return nil, fmt.Errorf("could not encode metadata: %w", e)
+	}
+	var with bj.Blob = *b
+	with.Metadata = raw
+	return &with, nil
+}
+
+// WriteStream implements StreamBlobSink.
+//
+// The first StreamBuffer bytes of the record are held back until it is complete,
+// so a record failing within them leaves nothing behind. A record failing after them is still closed,
+// with the body read so far and the error as MetaStreamError in its metadata.
+func (s *JsonStreamEncoder) WriteStream(b *bj.Blob, content io.Reader) error {
+	var limit int = s.StreamBuffer
+	if limit <= 0 {
+		limit = streamBufferDefault
+	}
+	s.hold.reset(s.Writer, limit)
+
+	var length int64
+	e := s.writeHead(&s.hold, b)
+	if nil == e {
+		length, e = s.writeBase64Stream(&s.hold, content)
+	}
+	if nil == e {
+		var tail bj.Blob = *b
+		tail.ContentLength = &length
+		e = s.writeTail(&s.hold, &tail)
+	}
+	if nil == e {
+		e = s.hold.flush()
+	} else if s.hold.flushed {
+		e = errors.Join(e, s.closeCut(b, length, e))
+	}
+	s.hold.reset(nil, 0)
+	if nil != e {
+		return fmt.Errorf("could not write blob %s: %w", b.Name, e)
+	}
+	return nil
+}
+
+// closeCut closes the record of the blob whose content failed after a part of the record was written.
+func (s *JsonStreamEncoder) closeCut(b *bj.Blob, length int64, cause error) error {
+	cut, e := withMetadata(b, MetaStreamError, cause.Error())
+	if nil != e {
+		return e
+	}
+	cut.ContentLength = &length
+	return s.writeTail(s.Writer, cut)
+}
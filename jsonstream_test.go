@@ -0,0 +1,254 @@
+package zip2jsons_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"strings"
+	"testing"
+	"time"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+	"github.com/takanoriyanagitani/go-zip2blobs2jsons"
+)
+
+func TestJsonStreamEncoder(t *testing.T) {
+	t.Parallel()
+
+	modified := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	pairs := []string{"a.txt", strings.Repeat("<x&y>", 1000), "dir/", "", "b.bin", "\x00\x01\x02"}
+	builders := map[string]bj.BlobBuilder{
+		"metadata":    {MaxBytes: 1 << 20, ContentType: "text/plain", Metadata: map[string]string{"tag": "<a&b>"}},
+		"no metadata": {MaxBytes: 1 << 20, LastModified: &modified},
+		"truncated":   {MaxBytes: 10},
+		"empty":       {},
+	}
+
+	for name, bldr := range builders {
+		for _, escapeHTML := range []bool{true, false} {
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+
+				expected := new(bytes.Buffer)
+				err := zip2jsons.ProcessZipArchive(newTestArchive(t, pairs...), zip2jsons.NewJsonEncoder(expected, escapeHTML), bldr)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				streamed := new(bytes.Buffer)
+				err = zip2jsons.ProcessZipArchive(newTestArchive(t, pairs...), zip2jsons.NewJsonStreamEncoder(streamed, escapeHTML), bldr)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if streamed.String() != expected.String() {
+					t.Errorf("Expected %s, got %s", expected, streamed)
+				}
+
+				raw := new(bytes.Buffer)
+				p := zip2jsons.Processor{Sink: zip2jsons.NewJsonStreamEncoder(raw, escapeHTML), Builder: bldr, Workers: 2}
+				err = p.Process(newTestArchive(t, pairs...))
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if raw.String() != expected.String() {
+					t.Errorf("Expected %s, got %s", expected, raw)
+				}
+
+				blobs := new(bytes.Buffer)
+				enc := zip2jsons.NewJsonStreamEncoder(blobs, escapeHTML)
+				err = zip2jsons.ProcessZipArchive(newTestArchive(t, pairs...), zip2jsons.BlobSinkFn(enc.Write), bldr)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if blobs.String() != expected.String() {
+					t.Errorf("Expected %s, got %s", expected, blobs)
+				}
+			})
+		}
+	}
+
+	t.Run("escaped body", func(t *testing.T) {
+		t.Parallel()
+
+		blob := &bj.Blob{Name: "x", Body: "not \"base64\" <>"}
+		expected := new(bytes.Buffer)
+		err := zip2jsons.NewJsonEncoder(expected, true).Write(blob)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		got := new(bytes.Buffer)
+		err = zip2jsons.NewJsonStreamEncoder(got, true).Write(blob)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got.String() != expected.String() {
+			t.Errorf("Expected %s, got %s", expected, got)
+		}
+	})
+}
+
+// newCorruptZip creates a zip of a good item followed by an item of the content with a wrong CRC.
+func newCorruptZip(t *testing.T, content string) zip2jsons.ZipArchive {
+	t.Helper()
+
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for _, item := range []struct {
+		name    string
+		content string
+		crc     uint32
+	}{
+		{"good.txt", "hello", crc32.ChecksumIEEE([]byte("hello"))},
+		{"bad.txt", content, crc32.ChecksumIEEE([]byte(content)) + 1},
+	} {
+		fw, err := w.CreateRaw(&zip.FileHeader{
+			Name:               item.name,
+			Method:             zip.Store,
+			CRC32:              item.crc,
+			CompressedSize64:   uint64(len(item.content)),
+			UncompressedSize64: uint64(len(item.content)),
+		})
+		if err != nil {
+			t.Fatalf("Failed to create %s: %v", item.name, err)
+		}
+		_, err = fw.Write([]byte(item.content))
+		if err != nil {
+			t.Fatalf("Failed to write %s: %v", item.name, err)
+		}
+	}
+	err := w.Close()
+	if err != nil {
+		t.Fatalf("Failed to close zip writer: %v", err)
+	}
+
+	arc, err := zip2jsons.ByteReader{Reader: bytes.NewReader(buf.Bytes())}.AsFileLike().ToZip()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return arc
+}
+
+// ndjsonBlobs decodes each line of the NDJSON as a blob.
+func ndjsonBlobs(t *testing.T, data []byte) []bj.Blob {
+	t.Helper()
+
+	var blobs []bj.Blob
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		var b bj.Blob
+		err := json.Unmarshal([]byte(line), &b)
+		if err != nil {
+			t.Fatalf("Invalid record %q: %v", line, err)
+		}
+		blobs = append(blobs, b)
+	}
+	return blobs
+}
+
+func TestJsonStreamEncoder_CorruptItem(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		streamBuffer int
+		content      string
+		records      int
+	}{
+		{"dropped", 0, "world", 3},
+		{"cut", 16, strings.Repeat("x", 5000), 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := new(bytes.Buffer)
+			enc := zip2jsons.NewJsonStreamEncoder(buf, true)
+			enc.StreamBuffer = tt.streamBuffer
+			p := zip2jsons.Processor{Sink: enc, Builder: bj.BlobBuilder{MaxBytes: 1 << 20}, Bracket: true}
+			err := p.Process(newCorruptZip(t, tt.content))
+			if !errors.Is(err, zip.ErrChecksum) {
+				t.Errorf("Expected a checksum error, got %v", err)
+			}
+
+			blobs := ndjsonBlobs(t, buf.Bytes())
+			if len(blobs) != tt.records || blobs[1].Name != "good.txt" {
+				t.Fatalf("Unexpected records: %+v", blobs)
+			}
+			var trl zip2jsons.ArchiveTrailer
+			err = json.Unmarshal(blobs[len(blobs)-1].Metadata, &trl)
+			if err != nil || trl.Status != zip2jsons.StatusAborted || trl.Items != 1 || trl.Failed != 1 {
+				t.Errorf("Unexpected trailer: %+v (%v)", trl, err)
+			}
+			if 4 == tt.records {
+				cut := blobs[2]
+				body, _ := base64.StdEncoding.DecodeString(cut.Body)
+				if cut.Name != "bad.txt" || nil == cut.ContentLength || *cut.ContentLength != int64(len(body)) {
+					t.Errorf("Unexpected cut record: %+v", cut)
+				}
+				if meta := blobMeta(t, &cut); !strings.Contains(meta[zip2jsons.MetaStreamError], "checksum") {
+					t.Errorf("Expected the stream error, got %v", meta)
+				}
+			}
+		})
+	}
+}
+
+func TestJsonStreamEncoder_WriteStream(t *testing.T) {
+	t.Parallel()
+
+	buf := new(bytes.Buffer)
+	blob := &bj.Blob{Name: "x.bin", Metadata: []byte(`{"tag":"a"}`)}
+	err := zip2jsons.NewJsonStreamEncoder(buf, true).WriteStream(blob, strings.NewReader("hello"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if nil != blob.ContentLength {
+		t.Errorf("Expected the blob to be left as it is, got %d", *blob.ContentLength)
+	}
+	blobs := ndjsonBlobs(t, buf.Bytes())
+	if len(blobs) != 1 || blobs[0].Body != "aGVsbG8=" || nil == blobs[0].ContentLength || *blobs[0].ContentLength != 5 {
+		t.Errorf("Unexpected records: %+v", blobs)
+	}
+}
+
+func TestProcessor_Truncated(t *testing.T) {
+	t.Parallel()
+
+	for _, workers := range []int{0, 2} {
+		buf := new(bytes.Buffer)
+		p := zip2jsons.Processor{
+			Sink:          zip2jsons.NewJsonStreamEncoder(buf, true),
+			Builder:       bj.BlobBuilder{MaxBytes: 3},
+			Workers:       workers,
+			MarkTruncated: true,
+		}
+		err := p.Process(newTestArchive(t, "long.txt", "hello", "short.txt", "hi"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		blobs := ndjsonBlobs(t, buf.Bytes())
+		if len(blobs) != 2 || blobs[0].Body != "aGVs" || *blobs[0].ContentLength != 3 {
+			t.Fatalf("Unexpected records: %+v", blobs)
+		}
+		if meta := blobMeta(t, &blobs[0]); meta[zip2jsons.MetaTruncated] != "true" {
+			t.Errorf("Expected the truncated metadata, got %v", meta)
+		}
+		if 0 < len(blobs[1].Metadata) && blobMeta(t, &blobs[1])[zip2jsons.MetaTruncated] != "" {
+			t.Errorf("Unexpected truncated metadata: %s", blobs[1].Metadata)
+		}
+	}
+
+	// not marked unless asked
+	buf := new(bytes.Buffer)
+	p := zip2jsons.Processor{Sink: zip2jsons.NewJsonStreamEncoder(buf, true), Builder: bj.BlobBuilder{MaxBytes: 3}}
+	err := p.Process(newTestArchive(t, "long.txt", "hello"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if blobs := ndjsonBlobs(t, buf.Bytes()); len(blobs) != 1 || 0 != len(blobs[0].Metadata) {
+		t.Errorf("Unexpected records: %+v", blobs)
+	}
+}
@@ -0,0 +1,163 @@
+package zip2jsons
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// ListFormat selects how EntryLister writes the entries.
+type ListFormat string
+
+// List formats.
+const (
+	// ListNdjson writes one JSON document per entry.
+	ListNdjson ListFormat = "ndjson"
+
+	// ListCSV writes the entries as CSV with a header row.
+	ListCSV ListFormat = "csv"
+
+	// ListTable writes the entries as an aligned table for humans.
+	ListTable ListFormat = "table"
+)
+
+// ParseListFormat parses the name of a ListFormat.
+func ParseListFormat(s string) (ListFormat, error) {
+	switch f := ListFormat(s); f {
+	case ListNdjson, ListCSV, ListTable:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown list format: %s", s)
+	}
+}
+
+// ListEntry is an Entry with the name of its archive.
+type ListEntry struct {
+	ZipName string `json:"zip_name"`
+
+	Entry
+}
+
+var listColumns = []string{
+	"zip_name", "name", "size", "compressed_size", "method", "crc32", "modified", "mode", "external_attrs", "comment",
+}
+
+// EntryLister writes the central directory entries of archives without reading their data.
+// It must be closed to flush the table format.
+type EntryLister struct {
+	format  ListFormat
+	enc     *json.Encoder
+	csv     *csv.Writer
+	tab     *tabwriter.Writer
+	started bool
+}
+
+// NewEntryLister creates an EntryLister writing to w in the format.
+func NewEntryLister(w io.Writer, format ListFormat) (*EntryLister, error) {
+	var l EntryLister = EntryLister{format: format}
+	switch format {
+	case ListNdjson:
+		l.enc = json.NewEncoder(w)
+	case ListCSV:
+		// the header is written even if no archive has entries
+		l.csv = csv.NewWriter(w)
+		e := l.csv.Write(listColumns)
+		if nil != e {
+			return nil, fmt.Errorf("could not write the list header: %w", e)
+		}
+	case ListTable:
+		l.tab = tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.StripEscape)
+	default:
+		return nil, fmt.Errorf("unknown list format: %s", format)
+	}
+	return &l, nil
+}
+
+func (l *EntryLister) row(e ListEntry) []string {
+	var method string = strconv.Itoa(int(e.Method))
+	if ListTable == l.format {
+		method = e.MethodName()
+	}
+	return []string{
+		e.ZipName,
+		e.Name,
+		strconv.FormatUint(e.Size, 10),
+		strconv.FormatUint(e.CompressedSize, 10),
+		method,
+		fmt.Sprintf("%08x", e.CRC32),
+		e.Modified.Format(time.RFC3339),
+		e.Mode,
+		fmt.Sprintf("%08x", e.ExternalAttrs),
+		e.Comment,
+	}
+}
+
+func (l *EntryLister) writeTableRow(cols []string) error {
+	var esc string = string([]byte{tabwriter.Escape})
+	for i, col := range cols {
+		var sep string = "\t"
+		if len(cols)-1 == i {
+			sep = "\n"
+		}
+		_, e := io.WriteString(l.tab, esc+col+esc+sep)
+		if nil != e {
+			return e
+		}
+	}
+	return nil
+}
+
+// Write writes a single entry.
+func (l *EntryLister) Write(e ListEntry) error {
+	var err error
+	switch l.format {
+	case ListNdjson:
+		err = l.enc.Encode(e)
+	case ListCSV:
+		err = l.csv.Write(l.row(e))
+	case ListTable:
+		if !l.started {
+			err = l.writeTableRow(listColumns)
+		}
+		if nil == err {
+			err = l.writeTableRow(l.row(e))
+		}
+	}
+	l.started = true
+	if nil != err {
+		return fmt.Errorf("could not write entry %s: %w", e.Name, err)
+	}
+	return nil
+}
+
+// List writes the entries of the archive.
+func (l *EntryLister) List(zipName string, arc ZipArchive) error {
+	for _, entry := range arc.Entries() {
+		e := l.Write(ListEntry{ZipName: zipName, Entry: entry})
+		if nil != e {
+			return e
+		}
+	}
+	if nil != l.csv {
+		l.csv.Flush()
+		return l.csv.Error()
+	}
+	return nil
+}
+
+// Close flushes the buffered entries. It does not close the underlying writer.
+func (l *EntryLister) Close() error {
+	switch l.format {
+	case ListCSV:
+		l.csv.Flush()
+		return l.csv.Error()
+	case ListTable:
+		return l.tab.Flush()
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,119 @@
+package zip2jsons_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/takanoriyanagitani/go-zip2blobs2jsons"
+)
+
+func TestZipArchive_Entries(t *testing.T) {
+	t.Parallel()
+
+	arc := newTestArchive(t, "a.txt", "hello", "d/", "")
+	entries := arc.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Name != "a.txt" || entries[0].Size != 5 || entries[0].CRC32 != 0x3610a686 {
+		t.Errorf("Unexpected entry: %+v", entries[0])
+	}
+	if entries[0].MethodName() != "deflate" {
+		t.Errorf("Expected deflate, got %s", entries[0].MethodName())
+	}
+	if !strings.HasPrefix(entries[1].Mode, "d") {
+		t.Errorf("Expected a directory mode, got %s", entries[1].Mode)
+	}
+}
+
+func listOutput(t *testing.T, format zip2jsons.ListFormat) string {
+	t.Helper()
+
+	buf := new(bytes.Buffer)
+	lister, err := zip2jsons.NewEntryLister(buf, format)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err = lister.List("x.zip", newTestArchive(t, "a.txt", "hello", "b\tc.txt", "world"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err = lister.Close()
+	if err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	return buf.String()
+}
+
+func TestEntryLister(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ndjson", func(t *testing.T) {
+		t.Parallel()
+
+		dec := json.NewDecoder(strings.NewReader(listOutput(t, zip2jsons.ListNdjson)))
+		var entry zip2jsons.ListEntry
+		err := dec.Decode(&entry)
+		if err != nil {
+			t.Fatalf("Failed to decode: %v", err)
+		}
+		if entry.ZipName != "x.zip" || entry.Name != "a.txt" || entry.Size != 5 {
+			t.Errorf("Unexpected entry: %+v", entry)
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		t.Parallel()
+
+		rows, err := csv.NewReader(strings.NewReader(listOutput(t, zip2jsons.ListCSV))).ReadAll()
+		if err != nil {
+			t.Fatalf("Invalid CSV: %v", err)
+		}
+		if len(rows) != 3 || rows[0][1] != "name" || rows[2][1] != "b\tc.txt" || rows[1][5] != "3610a686" {
+			t.Errorf("Unexpected rows: %v", rows)
+		}
+	})
+
+	t.Run("empty csv", func(t *testing.T) {
+		t.Parallel()
+
+		buf := new(bytes.Buffer)
+		lister, err := zip2jsons.NewEntryLister(buf, zip2jsons.ListCSV)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		err = lister.List("empty.zip", newTestArchive(t))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		err = lister.Close()
+		if err != nil {
+			t.Fatalf("Failed to close: %v", err)
+		}
+		rows, err := csv.NewReader(buf).ReadAll()
+		if err != nil {
+			t.Fatalf("Invalid CSV: %v", err)
+		}
+		if len(rows) != 1 || rows[0][0] != "zip_name" {
+			t.Errorf("Expected only the header, got %v", rows)
+		}
+	})
+
+	t.Run("table", func(t *testing.T) {
+		t.Parallel()
+
+		lines := strings.Split(strings.TrimSpace(listOutput(t, zip2jsons.ListTable)), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("Expected 3 lines, got %q", lines)
+		}
+		if strings.Index(lines[0], "size") != strings.Index(lines[1], "5 ") {
+			t.Errorf("Expected aligned columns, got %q", lines)
+		}
+		if strings.ContainsRune(lines[0], '\xff') {
+			t.Errorf("Escape characters not stripped: %q", lines[0])
+		}
+	})
+}
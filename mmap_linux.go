@@ -0,0 +1,55 @@
+//go:build linux && !tinygo
+
+package zip2jsons
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// mmapCloser unmaps the data once.
+type mmapCloser struct {
+	once sync.Once
+	data []byte
+}
+
+func (m *mmapCloser) Close() error {
+	var e error
+	m.once.Do(func() {
+		e = syscall.Munmap(m.data)
+	})
+	return e
+}
+
+// OpenMmapFileLike maps the local file read-only into memory and returns it as a FileLike.
+// Files which can not be mapped, such as empty files or pipes, are read with OpenFileLike instead.
+// The returned Closer unmaps the file; the FileLike must not be used afterwards.
+// Truncating the file while it is mapped crashes the process with SIGBUS.
+func OpenMmapFileLike(name string) (FileLike, io.Closer, error) {
+	f, e := os.Open(name) //nolint:gosec // the name is given by the user
+	if nil != e {
+		return FileLike{}, nil, fmt.Errorf("could not open %s: %w", name, e)
+	}
+	defer f.Close() //nolint:errcheck // the mapping stays valid after closing
+
+	st, e := f.Stat()
+	if nil != e {
+		return FileLike{}, nil, fmt.Errorf("could not stat %s: %w", name, e)
+	}
+	if !st.Mode().IsRegular() || 0 == st.Size() || math.MaxInt < st.Size() {
+		return OpenFileLike(name)
+	}
+
+	data, e := syscall.Mmap(int(f.Fd()), 0, int(st.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if nil != e {
+		return OpenFileLike(name)
+	}
+
+	var rdr ByteReader = ByteReader{Reader: bytes.NewReader(data)}
+	return rdr.AsFileLike(), &mmapCloser{data: data}, nil
+}
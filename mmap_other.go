@@ -0,0 +1,10 @@
+//go:build !linux || tinygo
+
+package zip2jsons
+
+import "io"
+
+// OpenMmapFileLike is OpenFileLike where mmap is not available.
+func OpenMmapFileLike(name string) (FileLike, io.Closer, error) {
+	return OpenFileLike(name)
+}
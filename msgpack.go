@@ -0,0 +1,161 @@
+package zip2jsons
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+)
+
+// msgpackTimestamp is the extension type of the MessagePack timestamp.
+const msgpackTimestamp = 0xff
+
+type msgpackWriter struct{ buf bytes.Buffer }
+
+// sized writes the smallest of the 8, 16 and 32 bit length prefixes.
+func (m *msgpackWriter) sized(n int, c8, c16, c32 byte) {
+	switch {
+	case n <= math.MaxUint8 && 0 != c8:
+		m.buf.Write([]byte{c8, byte(n)})
+	case n <= math.MaxUint16:
+		m.buf.WriteByte(c16)
+		m.buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		m.buf.WriteByte(c32)
+		m.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	}
+}
+
+func (m *msgpackWriter) null() { m.buf.WriteByte(0xc0) }
+
+func (m *msgpackWriter) boolean(b bool) {
+	if b {
+		m.buf.WriteByte(0xc3)
+		return
+	}
+	m.buf.WriteByte(0xc2)
+}
+
+func (m *msgpackWriter) integer(i int64) {
+	switch {
+	case 0 <= i && i <= math.MaxInt8:
+		m.buf.WriteByte(byte(i))
+	case -32 <= i && i < 0:
+		m.buf.WriteByte(byte(0xe0 | (i + 32)))
+	case 0 <= i && i <= math.MaxUint8:
+		m.buf.Write([]byte{0xcc, byte(i)})
+	case 0 <= i && i <= math.MaxUint16:
+		m.buf.WriteByte(0xcd)
+		m.buf.Write(binary.BigEndian.AppendUint16(nil, uint16(i)))
+	case 0 <= i && i <= math.MaxUint32:
+		m.buf.WriteByte(0xce)
+		m.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(i)))
+	case 0 <= i:
+		m.buf.WriteByte(0xcf)
+		m.buf.Write(binary.BigEndian.AppendUint64(nil, uint64(i)))
+	case math.MinInt8 <= i:
+		m.buf.Write([]byte{0xd0, byte(i)})
+	case math.MinInt16 <= i:
+		m.buf.WriteByte(0xd1)
+		m.buf.Write(binary.BigEndian.AppendUint16(nil, uint16(i)))
+	case math.MinInt32 <= i:
+		m.buf.WriteByte(0xd2)
+		m.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(i)))
+	default:
+		m.buf.WriteByte(0xd3)
+		m.buf.Write(binary.BigEndian.AppendUint64(nil, uint64(i)))
+	}
+}
+
+func (m *msgpackWriter) float(f float64) {
+	m.buf.WriteByte(0xcb)
+	m.buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(f)))
+}
+
+func (m *msgpackWriter) text(s string) {
+	if len(s) < 32 {
+		m.buf.WriteByte(0xa0 | byte(len(s)))
+	} else {
+		m.sized(len(s), 0xd9, 0xda, 0xdb)
+	}
+	m.buf.WriteString(s)
+}
+
+func (m *msgpackWriter) binary(b []byte) {
+	m.sized(len(b), 0xc4, 0xc5, 0xc6)
+	m.buf.Write(b)
+}
+
+func (m *msgpackWriter) timestamp(t time.Time) {
+	var sec int64 = t.Unix()
+	var nsec uint64 = uint64(t.Nanosecond())
+	switch {
+	case 0 == nsec && 0 <= sec && sec <= math.MaxUint32:
+		m.buf.Write([]byte{0xd6, msgpackTimestamp})
+		m.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(sec)))
+	case 0 <= sec && sec < 1<<34:
+		m.buf.Write([]byte{0xd7, msgpackTimestamp})
+		m.buf.Write(binary.BigEndian.AppendUint64(nil, nsec<<34|uint64(sec)))
+	default:
+		m.buf.Write([]byte{0xc7, 12, msgpackTimestamp})
+		m.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(nsec)))
+		m.buf.Write(binary.BigEndian.AppendUint64(nil, uint64(sec)))
+	}
+}
+
+func (m *msgpackWriter) arrayHeader(n int) {
+	if n < 16 {
+		m.buf.WriteByte(0x90 | byte(n))
+		return
+	}
+	m.sized(n, 0, 0xdc, 0xdd)
+}
+
+func (m *msgpackWriter) mapHeader(n int) {
+	if n < 16 {
+		m.buf.WriteByte(0x80 | byte(n))
+		return
+	}
+	m.sized(n, 0, 0xde, 0xdf)
+}
+
+// MsgpackEncoder writes the blobs as consecutive MessagePack maps
+// using the keys of the JSON schema, with the body as binary.
+type MsgpackEncoder struct {
+	io.Writer
+
+	mw msgpackWriter
+}
+
+// Write implements BlobSink.
+func (m *MsgpackEncoder) Write(b *bj.Blob) error {
+	body, e := blobBody(b)
+	if nil != e {
+		return e
+	}
+	return m.WriteRaw(b, body)
+}
+
+// WriteRaw implements RawBlobSink.
+func (m *MsgpackEncoder) WriteRaw(b *bj.Blob, content []byte) error {
+	fields, e := blobFields(b, content)
+	if nil != e {
+		return e
+	}
+
+	m.mw.buf.Reset()
+	e = writeFields(&m.mw, fields)
+	if nil != e {
+		return fmt.Errorf("could not encode blob %s: %w", b.Name, e)
+	}
+
+	_, e = m.Writer.Write(m.mw.buf.Bytes())
+	if nil != e {
+		return fmt.Errorf("could not write blob: %w", e)
+	}
+	return nil
+}
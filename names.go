@@ -0,0 +1,140 @@
+package zip2jsons
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// NameFinding describes why an item name is unsafe to use as a path.
+type NameFinding string
+
+// Name findings.
+const (
+	FindingEmpty       NameFinding = "empty"
+	FindingAbsolute    NameFinding = "absolute"
+	FindingDriveLetter NameFinding = "drive_letter"
+	FindingBackslash   NameFinding = "backslash"
+	FindingNul         NameFinding = "nul"
+	FindingParent      NameFinding = "parent"
+	FindingDuplicate   NameFinding = "duplicate"
+)
+
+// MetaNameFindings is the metadata key of the comma separated name findings.
+const MetaNameFindings = "name_findings"
+
+func hasDriveLetter(name string) bool {
+	return 2 <= len(name) && ':' == name[1] &&
+		('a' <= name[0] && name[0] <= 'z' || 'A' <= name[0] && name[0] <= 'Z')
+}
+
+// CheckName returns the findings of a single item name.
+func CheckName(name string) []NameFinding {
+	var findings []NameFinding
+	if "" == name {
+		findings = append(findings, FindingEmpty)
+	}
+	if strings.HasPrefix(name, "/") {
+		findings = append(findings, FindingAbsolute)
+	}
+	if hasDriveLetter(name) {
+		findings = append(findings, FindingDriveLetter)
+	}
+	if strings.Contains(name, "\\") {
+		findings = append(findings, FindingBackslash)
+	}
+	if strings.Contains(name, "\x00") {
+		findings = append(findings, FindingNul)
+	}
+
+	var cleaned string = path.Clean(strings.ReplaceAll(name, "\\", "/"))
+	if ".." == cleaned || strings.HasPrefix(cleaned, "../") {
+		findings = append(findings, FindingParent)
+	}
+	return findings
+}
+
+// CheckNames returns the findings of each name of an archive.
+// Every occurrence of a name appearing more than once is a FindingDuplicate.
+func CheckNames(names []string) [][]NameFinding {
+	var counts map[string]int = make(map[string]int, len(names))
+	for _, name := range names {
+		counts[name]++
+	}
+
+	var findings [][]NameFinding = make([][]NameFinding, len(names))
+	for i, name := range names {
+		findings[i] = CheckName(name)
+		if 1 < counts[name] {
+			findings[i] = append(findings[i], FindingDuplicate)
+		}
+	}
+	return findings
+}
+
+// JoinFindings joins the findings with commas.
+func JoinFindings(findings []NameFinding) string {
+	var parts []string = make([]string, 0, len(findings))
+	for _, f := range findings {
+		parts = append(parts, string(f))
+	}
+	return strings.Join(parts, ",")
+}
+
+// NormalizeName converts the backslashes to slashes, drops a drive letter
+// and cleans the name so that it neither is absolute nor escapes with "..".
+// The trailing slash of a directory is kept. NUL bytes are kept as they are.
+func NormalizeName(name string) string {
+	var slashed string = strings.ReplaceAll(name, "\\", "/")
+	if hasDriveLetter(slashed) {
+		slashed = slashed[2:]
+	}
+
+	// cleaning a rooted path drops the ".." elements above the root
+	var cleaned string = strings.TrimPrefix(path.Clean("/"+slashed), "/")
+	if "" != cleaned && strings.HasSuffix(slashed, "/") {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// NamePolicy decides how the Processor handles unsafe item names.
+type NamePolicy string
+
+// Name policies.
+const (
+	// NamesNone passes the names through unchecked.
+	NamesNone NamePolicy = ""
+
+	// NamesAnnotate adds the findings to the metadata under MetaNameFindings.
+	NamesAnnotate NamePolicy = "annotate"
+
+	// NamesNormalize replaces the names with NormalizeName and annotates the findings.
+	NamesNormalize NamePolicy = "normalize"
+
+	// NamesReject fails the archive with ErrUnsafeName before any item is written.
+	NamesReject NamePolicy = "reject"
+)
+
+// ParseNamePolicy parses the name of a NamePolicy. "none" is NamesNone.
+func ParseNamePolicy(s string) (NamePolicy, error) {
+	switch p := NamePolicy(s); p {
+	case "none":
+		return NamesNone, nil
+	case NamesNone, NamesAnnotate, NamesNormalize, NamesReject:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown name policy: %s", s)
+	}
+}
+
+// SafeJoin joins the slash-separated item name to the directory.
+// Names which are absolute, contain a drive letter, a backslash or a NUL byte,
+// or escape the directory are rejected with ErrUnsafeName.
+func SafeJoin(dir, name string) (string, error) {
+	if 0 < len(CheckName(name)) || "." == path.Clean(name) {
+		return "", fmt.Errorf("%w: %q", ErrUnsafeName, name)
+	}
+	return filepath.Join(dir, filepath.FromSlash(path.Clean(name))), nil
+}
@@ -0,0 +1,137 @@
+package zip2jsons_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+	"github.com/takanoriyanagitani/go-zip2blobs2jsons"
+)
+
+func TestSafeJoin(t *testing.T) {
+	t.Parallel()
+
+	safe := map[string]string{
+		"a.txt":       filepath.Join("out", "a.txt"),
+		"d/e/f.txt":   filepath.Join("out", "d", "e", "f.txt"),
+		"d/../g.txt":  filepath.Join("out", "g.txt"),
+		"./h/":        filepath.Join("out", "h"),
+		"dots..x.txt": filepath.Join("out", "dots..x.txt"),
+	}
+	for name, expected := range safe {
+		got, err := zip2jsons.SafeJoin("out", name)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+		}
+		if got != expected {
+			t.Errorf("%s: expected %s, got %s", name, expected, got)
+		}
+	}
+
+	unsafe := []string{
+		"",
+		".",
+		"../x",
+		"d/../../x",
+		"/etc/passwd",
+		"C:/x",
+		"c:x",
+		"d\\..\\..\\x",
+		"a\x00b",
+	}
+	for _, name := range unsafe {
+		_, err := zip2jsons.SafeJoin("out", name)
+		if !errors.Is(err, zip2jsons.ErrUnsafeName) {
+			t.Errorf("%q: expected ErrUnsafeName, got %v", name, err)
+		}
+	}
+}
+
+func TestCheckNames(t *testing.T) {
+	t.Parallel()
+
+	names := []string{"a.txt", "../../etc/passwd", "C:\\x", "a.txt", "/abs", "n\x00l"}
+	expected := []string{"duplicate", "parent", "drive_letter,backslash", "duplicate", "absolute", "nul"}
+	for i, findings := range zip2jsons.CheckNames(names) {
+		got := zip2jsons.JoinFindings(findings)
+		if got != expected[i] {
+			t.Errorf("%q: expected %q, got %q", names[i], expected[i], got)
+		}
+	}
+}
+
+func TestNormalizeName(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"a/b.txt":          "a/b.txt",
+		"../../etc/passwd": "etc/passwd",
+		"/abs/x":           "abs/x",
+		"C:\\dir\\x.txt":   "dir/x.txt",
+		"d\\..\\..\\e/":    "e/",
+		"./f//g":           "f/g",
+		"..":               "",
+	}
+	for name, expected := range cases {
+		got := zip2jsons.NormalizeName(name)
+		if got != expected {
+			t.Errorf("%q: expected %q, got %q", name, expected, got)
+		}
+	}
+}
+
+func TestProcessor_Names(t *testing.T) {
+	t.Parallel()
+
+	pairs := []string{"ok.txt", "fine", "..\\evil.txt", "bad"}
+	bldr := bj.BlobBuilder{MaxBytes: 1024}
+
+	t.Run("annotate", func(t *testing.T) {
+		t.Parallel()
+
+		var sink zip2jsons.SliceSink
+		p := zip2jsons.Processor{Sink: &sink, Builder: bldr, Names: zip2jsons.NamesAnnotate}
+		err := p.Process(newTestArchive(t, pairs...))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(sink.Blobs[0].Metadata) != 0 {
+			t.Errorf("Expected no metadata for a safe name, got %s", sink.Blobs[0].Metadata)
+		}
+		if string(sink.Blobs[1].Metadata) != `{"name_findings":"backslash,parent"}` {
+			t.Errorf("Unexpected metadata: %s", sink.Blobs[1].Metadata)
+		}
+		if sink.Blobs[1].Name != "..\\evil.txt" {
+			t.Errorf("Expected the original name, got %s", sink.Blobs[1].Name)
+		}
+	})
+
+	t.Run("normalize", func(t *testing.T) {
+		t.Parallel()
+
+		var sink zip2jsons.SliceSink
+		p := zip2jsons.Processor{Sink: &sink, Builder: bldr, Names: zip2jsons.NamesNormalize}
+		err := p.Process(newTestArchive(t, pairs...))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if sink.Blobs[1].Name != "evil.txt" {
+			t.Errorf("Expected evil.txt, got %s", sink.Blobs[1].Name)
+		}
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		t.Parallel()
+
+		var sink zip2jsons.SliceSink
+		p := zip2jsons.Processor{Sink: &sink, Builder: bldr, Names: zip2jsons.NamesReject, Workers: 4}
+		err := p.Process(newTestArchive(t, pairs...))
+		if !errors.Is(err, zip2jsons.ErrUnsafeName) {
+			t.Errorf("Expected ErrUnsafeName, got %v", err)
+		}
+		if len(sink.Blobs) != 0 {
+			t.Errorf("Expected nothing written, got %d blobs", len(sink.Blobs))
+		}
+	})
+}
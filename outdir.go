@@ -0,0 +1,161 @@
+package zip2jsons
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+)
+
+// ExistPolicy decides what happens when an output file already exists.
+type ExistPolicy string
+
+// Exist policies.
+const (
+	ExistFail      ExistPolicy = "fail"
+	ExistOverwrite ExistPolicy = "overwrite"
+	ExistSkip      ExistPolicy = "skip"
+)
+
+// ParseExistPolicy parses the name of an ExistPolicy.
+func ParseExistPolicy(s string) (ExistPolicy, error) {
+	switch p := ExistPolicy(s); p {
+	case ExistFail, ExistOverwrite, ExistSkip:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown exist policy: %s", s)
+	}
+}
+
+// WriteFileAtomic writes the file through a temporary file in the same directory
+// which is renamed once the write succeeded.
+// It returns false if the file exists and the policy is ExistSkip.
+func WriteFileAtomic(name string, policy ExistPolicy, write func(io.Writer) error) (bool, error) {
+	if ExistOverwrite != policy {
+		_, e := os.Lstat(name)
+		switch {
+		case nil == e && ExistSkip == policy:
+			return false, nil
+		case nil == e:
+			return false, fmt.Errorf("%w: %s", ErrFileExists, name)
+		case !errors.Is(e, fs.ErrNotExist):
+			return false, fmt.Errorf("could not check %s: %w", name, e)
+		}
+	}
+
+	dir, base := filepath.Split(name)
+	if "" == dir {
+		dir = "."
+	}
+	tmp, e := os.CreateTemp(dir, "."+base+".*.tmp")
+	if nil != e {
+		return false, fmt.Errorf("could not create temporary file for %s: %w", name, e)
+	}
+	var tmpName string = tmp.Name()
+	defer os.Remove(tmpName) //nolint:errcheck // already renamed on success
+
+	// best effort: the temporary file is created with 0600, which is not always supported to change
+	_ = tmp.Chmod(0o644)
+
+	e = write(tmp)
+	if nil != e {
+		_ = tmp.Close()
+		return false, e
+	}
+
+	e = tmp.Close()
+	if nil != e {
+		return false, fmt.Errorf("could not close %s: %w", tmpName, e)
+	}
+
+	e = os.Rename(tmpName, name)
+	if nil != e {
+		return false, fmt.Errorf("could not rename %s: %w", tmpName, e)
+	}
+	return true, nil
+}
+
+// ArchiveStem returns the base name of the zip file without its extension.
+func ArchiveStem(zipName string) string {
+	var base string = filepath.Base(filepath.FromSlash(zipName))
+	var stem string = strings.TrimSuffix(base, filepath.Ext(base))
+	if "" == stem || "." == stem || ".." == stem || string(filepath.Separator) == stem {
+		return "unknown"
+	}
+	return stem
+}
+
+// StemSet hands out distinct output stems to the archives of a run (see ArchiveStem).
+// A repeated stem is numbered: a/x.zip and b/x.zip get x and x-2.
+type StemSet struct{ used map[string]bool }
+
+// Stem returns the stem of the archive, numbered if already used.
+func (s *StemSet) Stem(zipName string) string {
+	if nil == s.used {
+		s.used = map[string]bool{}
+	}
+	var stem string = ArchiveStem(zipName)
+	var numbered string = stem
+	for i := 2; s.used[numbered]; i++ {
+		numbered = fmt.Sprintf("%s-%d", stem, i)
+	}
+	s.used[numbered] = true
+	return numbered
+}
+
+// Names of the files of the header and trailer records below EntryDirSink.Dir, without the .json extension.
+// They are reserved: an item with the same name fails or is skipped as the policy says.
+const (
+	EntryDirHeader  = ".zip2jsons-header"
+	EntryDirTrailer = ".zip2jsons-trailer"
+)
+
+// EntryDirSink writes each blob to its own JSON file below Dir,
+// mirroring the directory structure of the archive.
+// The blob named a/b.txt is written to Dir/a/b.txt.json.
+// Directory entries only create the directory.
+// The header and trailer records, which are named after the archive, are written to
+// EntryDirHeader and EntryDirTrailer.
+type EntryDirSink struct {
+	Dir        string
+	Policy     ExistPolicy
+	EscapeHTML bool
+}
+
+// Write implements BlobSink.
+func (d EntryDirSink) Write(b *bj.Blob) error {
+	var itemName string = b.Name
+	switch b.ContentType {
+	case ContentTypeHeader:
+		itemName = EntryDirHeader
+	case ContentTypeTrailer:
+		itemName = EntryDirTrailer
+	}
+	name, e := SafeJoin(d.Dir, itemName)
+	if nil != e {
+		return e
+	}
+
+	if strings.HasSuffix(itemName, "/") {
+		e = os.MkdirAll(name, 0o755)
+		if nil != e {
+			return fmt.Errorf("could not create directory %s: %w", name, e)
+		}
+		return nil
+	}
+
+	e = os.MkdirAll(filepath.Dir(name), 0o755)
+	if nil != e {
+		return fmt.Errorf("could not create directory for %s: %w", name, e)
+	}
+
+	_, e = WriteFileAtomic(name+".json", d.Policy, func(w io.Writer) error {
+		return NewJsonEncoder(w, d.EscapeHTML).EncodeBlob(b)
+	})
+	return e
+}
@@ -0,0 +1,179 @@
+package zip2jsons_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+	"github.com/takanoriyanagitani/go-zip2blobs2jsons"
+)
+
+func writeString(s string) func(io.Writer) error {
+	return func(w io.Writer) error {
+		_, err := io.WriteString(w, s)
+		return err
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	name := filepath.Join(dir, "out.ndjson")
+
+	written, err := zip2jsons.WriteFileAtomic(name, zip2jsons.ExistFail, writeString("first"))
+	if err != nil || !written {
+		t.Fatalf("Unexpected result: %v, %v", written, err)
+	}
+
+	_, err = zip2jsons.WriteFileAtomic(name, zip2jsons.ExistFail, writeString("second"))
+	if !errors.Is(err, zip2jsons.ErrFileExists) {
+		t.Errorf("Expected ErrFileExists, got %v", err)
+	}
+
+	written, err = zip2jsons.WriteFileAtomic(name, zip2jsons.ExistSkip, writeString("second"))
+	if err != nil || written {
+		t.Errorf("Expected skip, got %v, %v", written, err)
+	}
+
+	expectedErr := io.ErrUnexpectedEOF
+	_, err = zip2jsons.WriteFileAtomic(name, zip2jsons.ExistOverwrite, func(w io.Writer) error {
+		_, _ = io.WriteString(w, "partial")
+		return expectedErr
+	})
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("Expected %v, got %v", expectedErr, err)
+	}
+
+	content, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	if string(content) != "first" {
+		t.Errorf("Expected 'first', got '%s'", content)
+	}
+
+	_, err = zip2jsons.WriteFileAtomic(name, zip2jsons.ExistOverwrite, writeString("third"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	content, _ = os.ReadFile(name)
+	if string(content) != "third" {
+		t.Errorf("Expected 'third', got '%s'", content)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Expected no temporary files left, got %d entries", len(entries))
+	}
+}
+
+func TestEntryDirSink(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	var sink zip2jsons.EntryDirSink = zip2jsons.EntryDirSink{Dir: dir, Policy: zip2jsons.ExistFail}
+	arc := newTestArchive(t, "top.txt", "1", "d/", "", "d/e/f.txt", "2")
+	err := zip2jsons.ProcessZipArchive(arc, sink, bj.BlobBuilder{MaxBytes: 1024})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "d", "e", "f.txt.json"))
+	if err != nil {
+		t.Fatalf("Failed to read entry file: %v", err)
+	}
+	var blob bj.Blob
+	err = json.Unmarshal(content, &blob)
+	if err != nil {
+		t.Fatalf("Failed to decode entry file: %v", err)
+	}
+	if blob.Name != "d/e/f.txt" || blob.Body != "Mg==" {
+		t.Errorf("Unexpected blob: %+v", blob)
+	}
+
+	_, err = os.Stat(filepath.Join(dir, "top.txt.json"))
+	if err != nil {
+		t.Errorf("Expected top-level entry file: %v", err)
+	}
+
+	err = sink.Write(&bj.Blob{Name: "../escape.txt"})
+	if !errors.Is(err, zip2jsons.ErrUnsafeName) {
+		t.Errorf("Expected ErrUnsafeName, got %v", err)
+	}
+}
+
+func TestArchiveStem(t *testing.T) {
+	t.Parallel()
+
+	for name, expected := range map[string]string{
+		"/data/a.zip":    "a",
+		"b.tar.zip":      "b.tar",
+		"noext":          "noext",
+		"/":              "unknown",
+		"dir/.hidden":    "unknown",
+		"relative/c.ZIP": "c",
+	} {
+		if got := zip2jsons.ArchiveStem(name); got != expected {
+			t.Errorf("%s: expected %s, got %s", name, expected, got)
+		}
+	}
+}
+
+func TestEntryDirSink_Bracket(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	p := zip2jsons.Processor{
+		Sink: zip2jsons.EntryDirSink{Dir: dir, Policy: zip2jsons.ExistFail},
+		Builder: bj.BlobBuilder{
+			MaxBytes: 1024,
+			Metadata: map[string]string{zip2jsons.MetaZipName: "/data/x.zip"},
+		},
+		Bracket: true,
+	}
+	err := p.Process(newTestArchive(t, "a.txt", "1"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read output directory: %v", err)
+	}
+	var names []string
+	for _, ent := range entries {
+		names = append(names, ent.Name())
+	}
+	expected := []string{zip2jsons.EntryDirHeader + ".json", zip2jsons.EntryDirTrailer + ".json", "a.txt.json"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, names)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, zip2jsons.EntryDirTrailer+".json"))
+	if err != nil {
+		t.Fatalf("Failed to read trailer file: %v", err)
+	}
+	var blob bj.Blob
+	err = json.Unmarshal(content, &blob)
+	if err != nil || blob.Name != "/data/x.zip" || blob.ContentType != zip2jsons.ContentTypeTrailer {
+		t.Errorf("Unexpected trailer: %+v (%v)", blob, err)
+	}
+}
+
+func TestStemSet(t *testing.T) {
+	t.Parallel()
+
+	var stems zip2jsons.StemSet
+	expected := []string{"x", "x-2", "y", "x-3"}
+	for i, name := range []string{"a/x.zip", "b/x.zip", "y.zip", "c/x.zip"} {
+		if got := stems.Stem(name); got != expected[i] {
+			t.Errorf("%s: expected %s, got %s", name, expected[i], got)
+		}
+	}
+}
@@ -0,0 +1,424 @@
+package zip2jsons
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+)
+
+// Parquet enum values used by ParquetWriter.
+const (
+	parquetInt32     = 1
+	parquetInt64     = 2
+	parquetByteArray = 6
+
+	parquetRequired = 0
+
+	parquetUTF8            = 0
+	parquetTimestampMillis = 9
+
+	parquetPlain = 0
+	parquetRLE   = 3
+
+	parquetGzip = 2
+
+	parquetDataPage = 0
+)
+
+// Defaults of the ParquetWriter limits.
+const (
+	parquetDefaultRowGroupSize  = 4096
+	parquetDefaultRowGroupBytes = 64 << 20
+	parquetDefaultPageSize      = 1 << 20
+)
+
+var parquetMagic = []byte("PAR1")
+
+// parquetColumn buffers the PLAIN encoded values of a required column,
+// as the compressed pages of the column chunk followed by the values of the current page.
+type parquetColumn struct {
+	name      string
+	typ       int32
+	converted int32 // -1: none
+
+	pageSize int
+	values   bytes.Buffer
+	count    int32
+
+	pages        bytes.Buffer
+	uncompressed int64
+}
+
+// reserve finishes the current page if the value of the size does not fit in it.
+func (c *parquetColumn) reserve(size int) error {
+	if c.pageSize-c.values.Len() < size {
+		return c.finishPage()
+	}
+	return nil
+}
+
+func (c *parquetColumn) int32(v int32) error {
+	e := c.reserve(4)
+	if nil != e {
+		return e
+	}
+	c.values.Write(binary.LittleEndian.AppendUint32(nil, uint32(v)))
+	c.count++
+	return nil
+}
+
+func (c *parquetColumn) int64(v int64) error {
+	e := c.reserve(8)
+	if nil != e {
+		return e
+	}
+	c.values.Write(binary.LittleEndian.AppendUint64(nil, uint64(v)))
+	c.count++
+	return nil
+}
+
+func (c *parquetColumn) byteArray(b []byte) error {
+	if math.MaxInt32-4 < len(b) {
+		return fmt.Errorf("%w: parquet value of %d bytes", ErrUnsupported, len(b))
+	}
+	e := c.reserve(4 + len(b))
+	if nil != e {
+		return e
+	}
+	c.values.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(b)))) //nolint:gosec // checked above
+	c.values.Write(b)
+	c.count++
+	return nil
+}
+
+// buffered returns the number of bytes of the column chunk so far.
+func (c *parquetColumn) buffered() int64 {
+	return c.uncompressed + int64(c.values.Len())
+}
+
+// finishPage compresses the values of the current page and appends the page to the chunk.
+func (c *parquetColumn) finishPage() error {
+	if 0 == c.count {
+		return nil
+	}
+	data, e := gzipped(c.values.Bytes())
+	if nil != e {
+		return e
+	}
+	if math.MaxInt32 < len(data) {
+		return fmt.Errorf("%w: parquet page of %d bytes", ErrUnsupported, len(data))
+	}
+
+	var hdr thriftWriter
+	hdr.begin(0)
+	hdr.i32(1, parquetDataPage)
+	hdr.i32(2, int32(c.values.Len())) //nolint:gosec // below the page size, itself below MaxInt32
+	hdr.i32(3, int32(len(data)))
+	hdr.begin(5)
+	hdr.i32(1, c.count)
+	hdr.i32(2, parquetPlain)
+	hdr.i32(3, parquetRLE)
+	hdr.i32(4, parquetRLE)
+	hdr.end()
+	hdr.end()
+
+	c.pages.Write(hdr.buf.Bytes())
+	c.pages.Write(data)
+	c.uncompressed += int64(hdr.buf.Len() + c.values.Len())
+	c.values.Reset()
+	c.count = 0
+	return nil
+}
+
+// parquetChunk is the metadata of a written column chunk.
+type parquetChunk struct {
+	column       *parquetColumn
+	offset       int64
+	uncompressed int64
+	compressed   int64
+}
+
+type parquetRowGroup struct {
+	chunks []parquetChunk
+	rows   int64
+	bytes  int64
+}
+
+// ParquetWriter writes one row per blob to a Parquet file with the columns
+// name, size, compressed_size, crc32, modified (timestamp millis), method, content_type
+// and, optionally, body. The columns are gzip compressed.
+//
+// The zip columns are zero for blobs written without an Entry, such as the archive header.
+// The sink must be closed with CloseSink to write the file footer.
+type ParquetWriter struct {
+	io.Writer
+
+	// RowGroupSize is the number of rows of a row group. Defaults to 4096.
+	RowGroupSize int
+
+	// RowGroupBytes is the number of (uncompressed) value bytes after which a row group is written
+	// even if it has fewer rows than RowGroupSize. Defaults to 64 MiB.
+	RowGroupBytes int64
+
+	// PageSize is the number of (uncompressed) value bytes of a data page.
+	// A page holds a single value if the value is larger. Defaults to 1 MiB.
+	PageSize int
+
+	// Body adds the raw body as a binary column.
+	Body bool
+
+	columns   []*parquetColumn
+	rows      int64
+	total     int64
+	offset    int64
+	rowGroups []parquetRowGroup
+}
+
+func (p *ParquetWriter) init() error {
+	p.columns = []*parquetColumn{
+		{name: "name", typ: parquetByteArray, converted: parquetUTF8},
+		{name: "size", typ: parquetInt64, converted: -1},
+		{name: "compressed_size", typ: parquetInt64, converted: -1},
+		{name: "crc32", typ: parquetInt64, converted: -1},
+		{name: "modified", typ: parquetInt64, converted: parquetTimestampMillis},
+		{name: "method", typ: parquetInt32, converted: -1},
+		{name: "content_type", typ: parquetByteArray, converted: parquetUTF8},
+	}
+	if p.Body {
+		p.columns = append(p.columns, &parquetColumn{name: "body", typ: parquetByteArray, converted: -1})
+	}
+	var pageSize int = p.PageSize
+	if pageSize <= 0 {
+		pageSize = parquetDefaultPageSize
+	}
+	for _, c := range p.columns {
+		c.pageSize = min(pageSize, math.MaxInt32)
+	}
+	return p.write(parquetMagic)
+}
+
+func (p *ParquetWriter) write(b []byte) error {
+	n, e := p.Writer.Write(b)
+	p.offset += int64(n)
+	if nil != e {
+		return fmt.Errorf("could not write parquet data: %w", e)
+	}
+	return nil
+}
+
+// Write implements BlobSink. The zip columns of the row are zero.
+func (p *ParquetWriter) Write(b *bj.Blob) error {
+	var entry Entry = Entry{Name: b.Name}
+	if nil != b.LastModified {
+		entry.Modified = *b.LastModified
+	}
+	return p.WriteEntry(entry, b)
+}
+
+// WriteEntry implements EntrySink.
+func (p *ParquetWriter) WriteEntry(entry Entry, b *bj.Blob) error {
+	if nil == p.columns {
+		e := p.init()
+		if nil != e {
+			return e
+		}
+	}
+
+	var modified int64
+	if !entry.Modified.IsZero() {
+		modified = entry.Modified.UnixMilli()
+	}
+	var body []byte
+	if p.Body {
+		var e error
+		body, e = blobBody(b)
+		if nil != e {
+			return e
+		}
+		// checked before any column of the row is written
+		if math.MaxInt32-4 < len(body) {
+			return fmt.Errorf("%w: parquet value of %d bytes in %s", ErrUnsupported, len(body), b.Name)
+		}
+	}
+
+	e := p.columns[0].byteArray([]byte(b.Name))
+	if nil == e {
+		e = p.columns[1].int64(int64(entry.Size))
+	}
+	if nil == e {
+		e = p.columns[2].int64(int64(entry.CompressedSize))
+	}
+	if nil == e {
+		e = p.columns[3].int64(int64(entry.CRC32))
+	}
+	if nil == e {
+		e = p.columns[4].int64(modified)
+	}
+	if nil == e {
+		e = p.columns[5].int32(int32(entry.Method))
+	}
+	if nil == e {
+		e = p.columns[6].byteArray([]byte(b.ContentType))
+	}
+	if nil == e && p.Body {
+		e = p.columns[7].byteArray(body)
+	}
+	if nil != e {
+		return e
+	}
+	p.rows++
+
+	var size int = p.RowGroupSize
+	if size <= 0 {
+		size = parquetDefaultRowGroupSize
+	}
+	var limit int64 = p.RowGroupBytes
+	if limit <= 0 {
+		limit = parquetDefaultRowGroupBytes
+	}
+	var buffered int64
+	for _, c := range p.columns {
+		buffered += c.buffered()
+	}
+	if int64(size) <= p.rows || limit <= buffered {
+		return p.flushRowGroup()
+	}
+	return nil
+}
+
+func gzipped(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var gw *gzip.Writer = gzip.NewWriter(&buf)
+	_, e := gw.Write(data)
+	if nil == e {
+		e = gw.Close()
+	}
+	if nil != e {
+		return nil, fmt.Errorf("could not compress page: %w", e)
+	}
+	return buf.Bytes(), nil
+}
+
+// writeChunk writes the pages of the column as a column chunk.
+func (p *ParquetWriter) writeChunk(c *parquetColumn) (parquetChunk, error) {
+	e := c.finishPage()
+	if nil != e {
+		return parquetChunk{}, e
+	}
+
+	var chunk parquetChunk = parquetChunk{
+		column:       c,
+		offset:       p.offset,
+		uncompressed: c.uncompressed,
+		compressed:   int64(c.pages.Len()),
+	}
+	e = p.write(c.pages.Bytes())
+	c.pages.Reset()
+	c.uncompressed = 0
+	return chunk, e
+}
+
+func (p *ParquetWriter) flushRowGroup() error {
+	if 0 == p.rows {
+		return nil
+	}
+
+	var group parquetRowGroup = parquetRowGroup{rows: p.rows}
+	for _, c := range p.columns {
+		chunk, e := p.writeChunk(c)
+		if nil != e {
+			return e
+		}
+		group.chunks = append(group.chunks, chunk)
+		group.bytes += chunk.uncompressed
+	}
+	p.rowGroups = append(p.rowGroups, group)
+	p.total += p.rows
+	p.rows = 0
+	return nil
+}
+
+func (p *ParquetWriter) footer() []byte {
+	var t thriftWriter
+	t.begin(0)
+	t.i32(1, 1)
+
+	t.list(2, thriftStruct, len(p.columns)+1)
+	t.begin(0)
+	t.binary(4, "schema")
+	t.i32(5, int32(len(p.columns)))
+	t.end()
+	for _, c := range p.columns {
+		t.begin(0)
+		t.i32(1, c.typ)
+		t.i32(3, parquetRequired)
+		t.binary(4, c.name)
+		if 0 <= c.converted {
+			t.i32(6, c.converted)
+		}
+		t.end()
+	}
+
+	t.i64(3, p.total)
+
+	t.list(4, thriftStruct, len(p.rowGroups))
+	for _, g := range p.rowGroups {
+		t.begin(0)
+		t.list(1, thriftStruct, len(g.chunks))
+		for _, chunk := range g.chunks {
+			t.begin(0)
+			t.i64(2, chunk.offset)
+			t.begin(3)
+			t.i32(1, chunk.column.typ)
+			t.list(2, thriftI32, 2)
+			t.listI32(parquetPlain)
+			t.listI32(parquetRLE)
+			t.list(3, thriftBinary, 1)
+			t.listBinary(chunk.column.name)
+			t.i32(4, parquetGzip)
+			t.i64(5, g.rows)
+			t.i64(6, chunk.uncompressed)
+			t.i64(7, chunk.compressed)
+			t.i64(9, chunk.offset)
+			t.end()
+			t.end()
+		}
+		t.i64(2, g.bytes)
+		t.i64(3, g.rows)
+		t.end()
+	}
+
+	t.binary(6, "go-zip2blobs2jsons")
+	t.end()
+	return t.buf.Bytes()
+}
+
+// Close writes the pending rows and the file footer. It does not close the underlying writer.
+func (p *ParquetWriter) Close() error {
+	if nil == p.columns {
+		e := p.init()
+		if nil != e {
+			return e
+		}
+	}
+
+	e := p.flushRowGroup()
+	if nil != e {
+		return e
+	}
+
+	var meta []byte = p.footer()
+	e = p.write(meta)
+	if nil == e {
+		e = p.write(binary.LittleEndian.AppendUint32(nil, uint32(len(meta))))
+	}
+	if nil == e {
+		e = p.write(parquetMagic)
+	}
+	return e
+}
@@ -0,0 +1,252 @@
+package zip2jsons_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+	"github.com/takanoriyanagitani/go-zip2blobs2jsons"
+)
+
+// thriftReader decodes Thrift compact structs into maps keyed by the field id.
+type thriftReader struct {
+	t *testing.T
+	r *bytes.Reader
+}
+
+func (d thriftReader) uvarint() uint64 {
+	v, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		d.t.Fatalf("Failed to read varint: %v", err)
+	}
+	return v
+}
+
+func (d thriftReader) zigzag() int64 {
+	v := d.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (d thriftReader) value(typ byte) any {
+	switch typ {
+	case 1:
+		return true
+	case 2:
+		return false
+	case 5, 6:
+		return d.zigzag()
+	case 8:
+		b := make([]byte, d.uvarint())
+		_, _ = io.ReadFull(d.r, b)
+		return string(b)
+	case 9:
+		h, _ := d.r.ReadByte()
+		n := int(h >> 4)
+		if n == 15 {
+			n = int(d.uvarint())
+		}
+		list := make([]any, n)
+		for i := range list {
+			list[i] = d.value(h & 0x0f)
+		}
+		return list
+	case 12:
+		return d.structure()
+	}
+	d.t.Fatalf("Unsupported thrift type %d", typ)
+	return nil
+}
+
+func (d thriftReader) structure() map[int16]any {
+	m := map[int16]any{}
+	var last int16
+	for {
+		h, err := d.r.ReadByte()
+		if err != nil {
+			d.t.Fatalf("Failed to read field header: %v", err)
+		}
+		if h == 0 {
+			return m
+		}
+		if delta := int16(h >> 4); delta != 0 {
+			last += delta
+		} else {
+			last = int16(d.zigzag())
+		}
+		m[last] = d.value(h & 0x0f)
+	}
+}
+
+func parquetFooter(t *testing.T, data []byte) map[int16]any {
+	t.Helper()
+
+	if !bytes.HasPrefix(data, []byte("PAR1")) || !bytes.HasSuffix(data, []byte("PAR1")) {
+		t.Fatalf("Missing parquet magic")
+	}
+	size := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footer := data[len(data)-8-size : len(data)-8]
+	return thriftReader{t: t, r: bytes.NewReader(footer)}.structure()
+}
+
+// parquetPages decodes the PLAIN byte arrays of the data pages of the column chunk.
+func parquetPages(t *testing.T, data []byte, meta map[int16]any) [][]string {
+	t.Helper()
+
+	offset := meta[9].(int64)
+	r := bytes.NewReader(data[offset : offset+meta[7].(int64)])
+	var pages [][]string
+	for r.Len() > 0 {
+		hdr := thriftReader{t: t, r: r}.structure()
+		page := make([]byte, hdr[3].(int64))
+		_, _ = io.ReadFull(r, page)
+		gz, err := gzip.NewReader(bytes.NewReader(page))
+		if err != nil {
+			t.Fatalf("Invalid gzip page: %v", err)
+		}
+		plain, err := io.ReadAll(gz)
+		if err != nil {
+			t.Fatalf("Failed to decompress page: %v", err)
+		}
+		if int64(len(plain)) != hdr[2].(int64) {
+			t.Errorf("Expected a page of %d bytes, got %d", hdr[2], len(plain))
+		}
+
+		var values []string
+		for len(plain) > 0 {
+			n := binary.LittleEndian.Uint32(plain)
+			values = append(values, string(plain[4:4+n]))
+			plain = plain[4+n:]
+		}
+		if count := hdr[5].(map[int16]any)[1]; count != int64(len(values)) {
+			t.Errorf("Expected %d values in the page, got %v", len(values), count)
+		}
+		pages = append(pages, values)
+	}
+	return pages
+}
+
+// parquetStrings decodes the PLAIN byte arrays of the column chunk.
+func parquetStrings(t *testing.T, data []byte, meta map[int16]any) []string {
+	t.Helper()
+
+	var values []string
+	for _, page := range parquetPages(t, data, meta) {
+		values = append(values, page...)
+	}
+	return values
+}
+
+func TestParquetWriter(t *testing.T) {
+	t.Parallel()
+
+	buf := new(bytes.Buffer)
+	w := &zip2jsons.ParquetWriter{Writer: buf, RowGroupSize: 2, Body: true}
+	arc := newTestArchive(t, "a.txt", "alpha", "b.txt", "beta", "c.txt", "gamma")
+	err := zip2jsons.ProcessZipArchive(arc, w, bj.BlobBuilder{MaxBytes: 1024, ContentType: "text/plain"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err = zip2jsons.CloseSink(w)
+	if err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+
+	data := buf.Bytes()
+	footer := parquetFooter(t, data)
+	if footer[3] != int64(3) {
+		t.Errorf("Expected 3 rows, got %v", footer[3])
+	}
+
+	schema := footer[2].([]any)
+	var names []string
+	for _, el := range schema[1:] {
+		names = append(names, el.(map[int16]any)[4].(string))
+	}
+	expected := []string{"name", "size", "compressed_size", "crc32", "modified", "method", "content_type", "body"}
+	if len(names) != len(expected) {
+		t.Fatalf("Unexpected columns: %v", names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Errorf("Expected column %s, got %s", expected[i], names[i])
+		}
+	}
+
+	groups := footer[4].([]any)
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 row groups, got %d", len(groups))
+	}
+
+	var bodies []string
+	var itemNames []string
+	for _, g := range groups {
+		chunks := g.(map[int16]any)[1].([]any)
+		nameMeta := chunks[0].(map[int16]any)[3].(map[int16]any)
+		bodyMeta := chunks[7].(map[int16]any)[3].(map[int16]any)
+		itemNames = append(itemNames, parquetStrings(t, data, nameMeta)...)
+		bodies = append(bodies, parquetStrings(t, data, bodyMeta)...)
+	}
+	if len(itemNames) != 3 || itemNames[2] != "c.txt" {
+		t.Errorf("Unexpected names: %v", itemNames)
+	}
+	if len(bodies) != 3 || bodies[0] != "alpha" || bodies[2] != "gamma" {
+		t.Errorf("Unexpected bodies: %v", bodies)
+	}
+}
+
+func TestParquetWriter_Pages(t *testing.T) {
+	t.Parallel()
+
+	buf := new(bytes.Buffer)
+	w := &zip2jsons.ParquetWriter{Writer: buf, RowGroupBytes: 300, PageSize: 64, Body: true}
+	arc := newTestArchive(t,
+		"a.txt", strings.Repeat("a", 40),
+		"b.txt", strings.Repeat("b", 40),
+		"c.txt", strings.Repeat("c", 100),
+		"d.txt", "d",
+	)
+	err := zip2jsons.ProcessZipArchive(arc, w, bj.BlobBuilder{MaxBytes: 1024, ContentType: "text/plain"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err = zip2jsons.CloseSink(w)
+	if err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+
+	data := buf.Bytes()
+	groups := parquetFooter(t, data)[4].([]any)
+	var rows []int64
+	var pages [][]string
+	for _, g := range groups {
+		rows = append(rows, g.(map[int16]any)[3].(int64))
+		bodyMeta := g.(map[int16]any)[1].([]any)[7].(map[int16]any)[3].(map[int16]any)
+		pages = append(pages, parquetPages(t, data, bodyMeta)...)
+	}
+	// the third row reaches the byte limit of the row group
+	if len(rows) != 2 || rows[0] != 3 || rows[1] != 1 {
+		t.Errorf("Unexpected rows of the row groups: %v", rows)
+	}
+	// a body per page as two do not fit, the larger one alone
+	if len(pages) != 4 || len(pages[2]) != 1 || pages[2][0] != strings.Repeat("c", 100) || pages[3][0] != "d" {
+		t.Errorf("Unexpected pages: %v", pages)
+	}
+}
+
+func TestParquetWriter_Empty(t *testing.T) {
+	t.Parallel()
+
+	buf := new(bytes.Buffer)
+	err := zip2jsons.CloseSink(&zip2jsons.ParquetWriter{Writer: buf})
+	if err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	footer := parquetFooter(t, buf.Bytes())
+	if footer[3] != int64(0) || len(footer[2].([]any)) != 8 {
+		t.Errorf("Unexpected footer: %v", footer)
+	}
+}
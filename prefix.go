@@ -0,0 +1,112 @@
+package zip2jsons
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"time"
+)
+
+// PrefixName is the name of the pseudo item holding the data before the zip (see Processor.PrefixItem).
+const PrefixName = "(prefix)"
+
+// MetaPrefix is the metadata key marking the pseudo item of the prefix.
+const MetaPrefix = "prefix"
+
+// zipPrefix returns the length of the data before the first local header, and the shift of the offsets:
+// the length of the data before the zip if its offsets are relative to its own start.
+func (l FileLike) zipPrefix() (prefix int64, shift int64, e error) {
+	rec, locator, at, e := endRecord(l)
+	if nil != e {
+		return 0, 0, e
+	}
+	var end zipEnd = parseEnd(rec)
+	var endAt int64 = at
+	if isZip64Locator(locator) {
+		// the offset in the locator may be shifted too: the record is expected right before it
+		var z64 []byte
+		z64, endAt, e = zip64EndBefore(l, at-zip64LocatorLen)
+		if nil != e {
+			return 0, 0, e
+		}
+		e = end.zip64(z64)
+		if nil != e {
+			return 0, 0, e
+		}
+	}
+	if 0 != end.disk {
+		return 0, 0, fmt.Errorf("%w: split archive of %d disks", ErrUnsupported, end.disk+1)
+	}
+
+	if uint64(endAt) < end.cdSize || uint64(endAt)-end.cdSize < end.cdOff { //nolint:gosec // not negative
+		return 0, 0, fmt.Errorf("%w: central directory beyond its end", ErrNewReader)
+	}
+	var cdStart int64 = endAt - int64(end.cdSize) //nolint:gosec // checked above
+	shift = cdStart - int64(end.cdOff)            //nolint:gosec // checked above
+
+	var cd []byte = make([]byte, end.cdSize)
+	_, e = l.ReadAt(cd, cdStart)
+	if nil != e {
+		return 0, 0, fmt.Errorf("could not read the central directory: %w", e)
+	}
+	// the central directory itself if there are no items
+	var first uint64 = end.cdOff
+	e = eachRecord(cd, end.entries, func(rec []byte) error {
+		first = min(first, parseRecord(rec).offset)
+		return nil
+	})
+	if nil != e {
+		return 0, 0, e
+	}
+	return shift + int64(first), shift, nil //nolint:gosec // below cdOff
+}
+
+// ToZipPrefixed is like ToZip but tolerates data before the zip,
+// such as the stub of a self-extracting archive or a prepended script.
+//
+// The end of central directory record is searched from the end of the file, and the offsets are corrected
+// if they are relative to the start of the zip rather than of the file.
+// The length of the data before the first local header is set to ZipArchive.Prefix.
+func (l FileLike) ToZipPrefixed() (ZipArchive, error) {
+	prefix, shift, e := l.zipPrefix()
+	if nil != e {
+		return ZipArchive{}, e
+	}
+
+	var data FileLike = FileLike{
+		ReaderAt: io.NewSectionReader(l.ReaderAt, shift, l.Size-shift),
+		Size:     l.Size - shift,
+	}
+	arc, e := data.ToZip()
+	if nil != e {
+		return ZipArchive{}, e
+	}
+	arc.Size = l.Size
+	arc.Prefix = prefix
+	arc.prefix = l.ReaderAt
+	return arc, nil
+}
+
+// prefixEntry returns the entry of the pseudo item of the prefix.
+func (a ZipArchive) prefixEntry() Entry {
+	return Entry{
+		Name:           PrefixName,
+		Size:           uint64(a.Prefix), //nolint:gosec // not negative
+		CompressedSize: uint64(a.Prefix), //nolint:gosec // not negative
+		Mode:           fs.FileMode(0o644).String(),
+	}
+}
+
+// writePrefix writes the data before the zip as a pseudo item with the index -1.
+func (p Processor) writePrefix(ctx context.Context, arc ZipArchive) error {
+	var info ItemInfo = ItemInfo{Index: -1, Total: len(arc.Files()), Name: PrefixName}
+	var content io.Reader = io.NewSectionReader(arc.prefix, 0, arc.Prefix)
+	var checks map[string]string = map[string]string{MetaPrefix: "true"}
+
+	e := p.readItem(ctx, info, time.Time{}, arc.Prefix, content, checks, arc.prefixEntry)
+	if nil != e {
+		return fmt.Errorf("error processing the prefix: %w", e)
+	}
+	return nil
+}
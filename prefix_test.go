@@ -0,0 +1,191 @@
+package zip2jsons_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+	"github.com/takanoriyanagitani/go-zip2blobs2jsons"
+)
+
+const sfxStub = "#!/bin/sh\necho \"self-extracting stub\"\nexit 0\n"
+
+// newPrefixedZip returns a zip after the stub.
+// The offsets are relative to the start of the file if adjusted, as with zip -A.
+func newPrefixedZip(t *testing.T, adjusted bool) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	if adjusted {
+		w.SetOffset(int64(len(sfxStub)))
+	}
+	for _, name := range []string{"hello.txt", "world.txt"} {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+		_, err = fw.Write([]byte(strings.TrimSuffix(name, ".txt")))
+		if err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	err := w.Close()
+	if err != nil {
+		t.Fatalf("Failed to close zip: %v", err)
+	}
+	return append([]byte(sfxStub), buf.Bytes()...)
+}
+
+// withZip64Extensible adds an extensible data sector to the zip64 end of central directory record.
+func withZip64Extensible(t *testing.T, dat []byte, ext []byte) []byte {
+	t.Helper()
+
+	at := bytes.LastIndex(dat, []byte{0x50, 0x4b, 0x06, 0x06})
+	if at < 0 {
+		t.Fatalf("Missing zip64 end of central directory")
+	}
+	out := bytes.Clone(dat[:at+56])
+	binary.LittleEndian.PutUint64(out[at+4:], binary.LittleEndian.Uint64(out[at+4:])+uint64(len(ext)))
+	out = append(out, ext...)
+	return append(out, dat[at+56:]...)
+}
+
+func readZipItem(t *testing.T, arc zip2jsons.ZipArchive, i int) string {
+	t.Helper()
+
+	rc, err := arc.Files()[i].Open()
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", arc.Files()[i].Name, err)
+	}
+	defer rc.Close() //nolint:errcheck
+
+	dat, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", arc.Files()[i].Name, err)
+	}
+	return string(dat)
+}
+
+func TestFileLike_ToZipPrefixed(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    []byte
+		expected int64
+		content  string
+	}{
+		{"relative offsets", newPrefixedZip(t, false), int64(len(sfxStub)), "world"},
+		{"adjusted offsets", newPrefixedZip(t, true), int64(len(sfxStub)), "world"},
+		{"no prefix", newZipBytes(t, 0, "hello.txt", "hello"), 0, "hello"},
+		{"zip64", read7z(t, "prefixed64.zip"), int64(len(sfxStub)), "hello, prefix\n"},
+		{
+			"zip64 extensible data",
+			withZip64Extensible(t, read7z(t, "prefixed64.zip"), []byte("\x01\x00\x04\x00data")),
+			int64(len(sfxStub)),
+			"hello, prefix\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			f := zip2jsons.ByteReader{Reader: bytes.NewReader(tt.input)}.AsFileLike()
+			arc, err := f.ToZipPrefixed()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if arc.Prefix != tt.expected {
+				t.Errorf("Expected prefix %d, got %d", tt.expected, arc.Prefix)
+			}
+			if arc.Size != int64(len(tt.input)) {
+				t.Errorf("Expected size %d, got %d", len(tt.input), arc.Size)
+			}
+			if content := readZipItem(t, arc, len(arc.Files())-1); content != tt.content {
+				t.Errorf("Expected %q, got %q", tt.content, content)
+			}
+		})
+	}
+
+	t.Run("not a zip", func(t *testing.T) {
+		t.Parallel()
+
+		f := zip2jsons.ByteReader{Reader: bytes.NewReader([]byte(sfxStub))}.AsFileLike()
+		_, err := f.ToZipPrefixed()
+		if !errors.Is(err, zip2jsons.ErrNewReader) {
+			t.Errorf("Expected ErrNewReader, got %v", err)
+		}
+	})
+}
+
+func TestProcessor_PrefixItem(t *testing.T) {
+	t.Parallel()
+
+	f := zip2jsons.ByteReader{Reader: bytes.NewReader(read7z(t, "prefixed64.zip"))}.AsFileLike()
+	_, err := f.ToZip()
+	if err == nil {
+		t.Errorf("Expected the zip64 archive to need the prefixed mode")
+	}
+	arc, err := f.ToZipPrefixed()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var sink zip2jsons.SliceSink
+	p := zip2jsons.Processor{Sink: &sink, Builder: bj.BlobBuilder{MaxBytes: 1024}, Bracket: true, PrefixItem: true}
+	err = p.Process(arc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(sink.Blobs) != 4 {
+		t.Fatalf("Expected 4 records, got %d", len(sink.Blobs))
+	}
+
+	var hdr zip2jsons.ArchiveHeader
+	err = json.Unmarshal(sink.Blobs[0].Metadata, &hdr)
+	if err != nil || hdr.Prefix != int64(len(sfxStub)) || hdr.Entries != 1 {
+		t.Errorf("Unexpected header: %+v (%v)", hdr, err)
+	}
+
+	prefix := sink.Blobs[1]
+	if prefix.Name != zip2jsons.PrefixName || prefix.Body != base64.StdEncoding.EncodeToString([]byte(sfxStub)) {
+		t.Errorf("Unexpected prefix item: %+v", prefix)
+	}
+	if prefix.LastModified != nil {
+		t.Errorf("Expected no modification time, got %v", prefix.LastModified)
+	}
+	if meta := blobMeta(t, prefix); meta[zip2jsons.MetaPrefix] != "true" {
+		t.Errorf("Expected the prefix metadata, got %v", meta)
+	}
+	if sink.Blobs[2].Name != "hello.txt" {
+		t.Errorf("Unexpected item: %s", sink.Blobs[2].Name)
+	}
+
+	var trl zip2jsons.ArchiveTrailer
+	err = json.Unmarshal(sink.Blobs[3].Metadata, &trl)
+	if err != nil || trl.Items != 2 {
+		t.Errorf("Unexpected trailer: %+v (%v)", trl, err)
+	}
+}
+
+func TestReader_ProcessPrefixed(t *testing.T) {
+	t.Parallel()
+
+	var sink zip2jsons.SliceSink
+	r := zip2jsons.Reader{Reader: bytes.NewReader(newPrefixedZip(t, false)), Prefixed: true}
+	err := r.ProcessAuto(1<<20, zip2jsons.Processor{Sink: &sink, Builder: bj.BlobBuilder{MaxBytes: 1024}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(sink.Blobs) != 2 || sink.Blobs[1].Name != "world.txt" || sink.Blobs[1].Body != "d29ybGQ=" {
+		t.Errorf("Unexpected blobs: %d", len(sink.Blobs))
+	}
+}
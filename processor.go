@@ -0,0 +1,525 @@
+package zip2jsons
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"strconv"
+	"sync"
+	"time"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+)
+
+// Processor converts the items of a zip archive into blobs and writes them to a sink.
+//
+// The placeholders in the Builder.Metadata values are expanded for each item (see ItemInfo.Expand).
+//
+// Builder.MaxBytes limits the raw content of each item, before the base64 encoding.
+// The items declaring a larger size are cut at the limit (see MarkTruncated).
+//
+// Sinks implementing StreamBlobSink receive the items while they are read when converting sequentially,
+// and sinks implementing RawBlobSink receive the raw content instead of the base64 Body.
+type Processor struct {
+	Sink    BlobSink
+	Builder bj.BlobBuilder
+
+	// Workers is the number of items converted concurrently.
+	// Values below 2 convert the items sequentially.
+	// A StreamBlobSink is only streamed to sequentially: with workers, it receives
+	// the converted items through WriteRaw or Write like the other sinks.
+	Workers int
+
+	// MaxInFlight bounds the number of blobs converted but not yet written.
+	// Each blob holds at most Builder.MaxBytes of content, base64 encoded unless written to a RawBlobSink.
+	// Defaults to twice the number of workers.
+	MaxInFlight int
+
+	// Unordered writes the blobs in completion order instead of the central-directory order.
+	Unordered bool
+
+	// ItemTimeout limits the time spent converting a single item. Zero means no limit.
+	ItemTimeout time.Duration
+
+	// MarkTruncated adds the MetaTruncated metadata to the items whose declared size exceeds Builder.MaxBytes.
+	MarkTruncated bool
+
+	// DerivedMetadata adds the fields of ItemInfo.Derived to the metadata of each blob.
+	// The builder metadata takes precedence over the derived fields.
+	DerivedMetadata bool
+
+	// Bracket writes an ArchiveHeader record before the items and an ArchiveTrailer record after them.
+	Bracket bool
+
+	// Names decides how unsafe and duplicate item names are handled.
+	Names NamePolicy
+
+	// Duplicates decides how the names appearing more than once are handled.
+	// The skipped duplicates are counted in the trailer.
+	Duplicates DuplicatePolicy
+
+	// DuplicateFold selects how the names are compared for Duplicates.
+	DuplicateFold NameFold
+
+	// PrefixItem writes the data before the first local header of an archive read by FileLike.ToZipPrefixed
+	// as a pseudo item named PrefixName, with the MetaPrefix metadata and a zero modification time,
+	// before the other items.
+	PrefixItem bool
+
+	stats       *archiveStats
+	findings    [][]NameFinding
+	selected    []int
+	occurrences map[int]int
+}
+
+// MetaTruncated is the metadata key marking an item whose declared size exceeds Builder.MaxBytes (see Processor.MarkTruncated).
+const MetaTruncated = "truncated"
+
+// itemBuilder returns the builder of the item of the declared size.
+// The checks are added to the metadata, taking precedence over the builder metadata.
+func (p Processor) itemBuilder(info ItemInfo, checks map[string]string, size int64) bj.BlobBuilder {
+	var bldr bj.BlobBuilder = p.Builder
+	if p.MarkTruncated && max(bldr.MaxBytes, 0) < size {
+		checks = maps.Clone(checks)
+		if nil == checks {
+			checks = map[string]string{}
+		}
+		checks[MetaTruncated] = "true"
+	}
+	if hasTemplate(bldr.Metadata) {
+		bldr.Metadata = info.ExpandMetadata(bldr.Metadata)
+	}
+	if p.DerivedMetadata {
+		var meta map[string]string = info.Derived()
+		maps.Copy(meta, bldr.Metadata)
+		bldr.Metadata = meta
+	}
+	if 0 < len(checks) {
+		var meta map[string]string = maps.Clone(bldr.Metadata)
+		if nil == meta {
+			meta = map[string]string{}
+		}
+		maps.Copy(meta, checks)
+		bldr.Metadata = meta
+	}
+	return bldr
+}
+
+// checks returns the name checks of the i-th file.
+func (p Processor) checks(i int) map[string]string {
+	var checks map[string]string = map[string]string{}
+	if 0 < len(p.findings) && 0 < len(p.findings[i]) {
+		checks[MetaNameFindings] = JoinFindings(p.findings[i])
+	}
+	if occurrence, ok := p.occurrences[i]; ok {
+		checks[MetaOccurrence] = strconv.Itoa(occurrence)
+	}
+	return checks
+}
+
+// itemInfo describes the i-th file, with the name normalized if the policy says so.
+func (p Processor) itemInfo(files []*zip.File, i int) ItemInfo {
+	var name string = files[i].Name
+	if NamesNormalize == p.Names {
+		name = NormalizeName(name)
+	}
+	return ItemInfo{Index: i, Total: len(files), Name: name}
+}
+
+// checkNames returns the name findings of the items, or rejects the archive if the policy says so.
+func (p Processor) checkNames(names []string) ([][]NameFinding, error) {
+	var findings [][]NameFinding = CheckNames(names)
+
+	if NamesReject != p.Names {
+		return findings, nil
+	}
+	for i, found := range findings {
+		if 0 < len(found) {
+			return nil, fmt.Errorf("%w: %q (%s)", ErrUnsafeName, names[i], JoinFindings(found))
+		}
+	}
+	return findings, nil
+}
+
+// prepare checks the item names and selects the items to convert, as the policies say.
+// It returns the processor with the findings and the selection.
+func (p Processor) prepare(names []string) (Processor, error) {
+	if NamesNone != p.Names {
+		findings, e := p.checkNames(names)
+		if nil != e {
+			return p, e
+		}
+		p.findings = findings
+	}
+
+	if DuplicatesNone != p.Duplicates {
+		selected, occurrences, e := p.duplicates(names)
+		if nil != e {
+			return p, e
+		}
+		p.selected = selected
+		p.occurrences = occurrences
+		p.stats.skipped = len(names) - len(selected)
+	}
+	return p, nil
+}
+
+// converted is a converted item.
+// The content is set instead of the blob body for a RawBlobSink, and is returned to the pool once written.
+type converted struct {
+	blob    *bj.Blob
+	content *bytes.Buffer
+}
+
+// release returns the content to the pool, if any.
+func (c converted) release() {
+	if nil != c.content {
+		putBody(c.content)
+	}
+}
+
+func (p Processor) rawSink() (RawBlobSink, bool) {
+	if _, ok := p.Sink.(EntrySink); ok {
+		return nil, false
+	}
+	rs, ok := p.Sink.(RawBlobSink)
+	return rs, ok
+}
+
+func (p Processor) streamSink() (StreamBlobSink, bool) {
+	if _, ok := p.Sink.(EntrySink); ok {
+		return nil, false
+	}
+	ss, ok := p.Sink.(StreamBlobSink)
+	return ss, ok
+}
+
+// stream converts the item while writing it to the sink.
+func (p Processor) stream(ctx context.Context, info ItemInfo, zfile *zip.File, ss StreamBlobSink) error {
+	if 0 < p.ItemTimeout {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.ItemTimeout)
+		defer cancel()
+	}
+
+	var zitem ZipItem = ZipItem{File: zfile}
+	var bldr bj.BlobBuilder = p.itemBuilder(info, p.checks(info.Index), zitem.size())
+	var modified time.Time = zitem.Modified()
+	bldr.LastModified = &modified
+	var name string = zitem.Name()
+	if NamesNormalize == p.Names {
+		name = info.Name
+	}
+	blb, e := headerBlob(bldr, name, 0)
+	if nil != e {
+		p.stats.failed++
+		return fmt.Errorf("could not convert zip item to blob: %w", e)
+	}
+
+	rc, e := zfile.Open()
+	if nil != e {
+		p.stats.failed++
+		return fmt.Errorf("could not open zip file %s: %w", name, e)
+	}
+	defer rc.Close() //nolint:errcheck// the "file" is read only
+
+	return p.streamContent(ctx, blb, rc, ss)
+}
+
+// streamContent writes the blob with the content to the sink, and sets its ContentLength to the bytes read.
+// As with ToBlob, at most Builder.MaxBytes of the content are read.
+func (p Processor) streamContent(ctx context.Context, blb *bj.Blob, content io.Reader, ss StreamBlobSink) error {
+	var maxBytes int64 = max(p.Builder.MaxBytes, 0)
+	var limited *io.LimitedReader = &io.LimitedReader{R: content, N: maxBytes}
+	e := ss.WriteStream(blb, ContextReader{Reader: limited, Context: ctx})
+	var length int64 = maxBytes - limited.N
+	blb.ContentLength = &length
+	if nil != ctx.Err() {
+		p.stats.failed++
+		return canceled(ctx)
+	}
+	if nil != e {
+		p.stats.failed++
+		return fmt.Errorf("could not write blob: %w", e)
+	}
+	p.stats.written(blb)
+	return nil
+}
+
+func (p Processor) convert(ctx context.Context, info ItemInfo, zfile *zip.File) (converted, error) {
+	if 0 < p.ItemTimeout {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.ItemTimeout)
+		defer cancel()
+	}
+
+	zitem := ZipItem{File: zfile}
+	var item converted
+	var e error
+	if _, ok := p.rawSink(); ok {
+		item.blob, item.content, e = zitem.toRaw(ctx, p.itemBuilder(info, p.checks(info.Index), zitem.size()))
+	} else {
+		item.blob, e = zitem.ToBlobContext(ctx, p.itemBuilder(info, p.checks(info.Index), zitem.size()))
+	}
+	if nil != e {
+		return converted{}, fmt.Errorf("could not convert zip item to blob: %w", e)
+	}
+	if NamesNormalize == p.Names {
+		item.blob.Name = info.Name
+	}
+	return item, nil
+}
+
+func zipEntry(zfile *zip.File) func() Entry {
+	return func() Entry { return NewEntry(&zfile.FileHeader) }
+}
+
+// write writes the converted item; the entry is only created for an EntrySink.
+func (p Processor) write(item converted, entry func() Entry) error {
+	var blb *bj.Blob = item.blob
+	var e error
+	if rs, ok := p.rawSink(); ok && nil != item.content {
+		e = rs.WriteRaw(blb, item.content.Bytes())
+		putBody(item.content)
+	} else if es, ok := p.Sink.(EntrySink); ok {
+		var ent Entry = entry()
+		ent.Name = blb.Name
+		e = es.WriteEntry(ent, blb)
+	} else {
+		e = p.Sink.Write(blb)
+	}
+	if nil != e {
+		return fmt.Errorf("could not write blob: %w", e)
+	}
+	p.stats.written(blb)
+	return nil
+}
+
+func (p Processor) maxInFlight() int {
+	if 0 < p.MaxInFlight {
+		return p.MaxInFlight
+	}
+	return 2 * p.Workers
+}
+
+// Process converts the items of the archive and flushes the sink.
+func (p Processor) Process(arc ZipArchive) error {
+	return p.ProcessContext(context.Background(), arc)
+}
+
+// ProcessContext is like Process but stops once the context is done.
+// The cancellation is checked between the items and while reading them.
+func (p Processor) ProcessContext(ctx context.Context, arc ZipArchive) error {
+	p.stats = &archiveStats{started: time.Now()}
+
+	p, e := p.prepare(arc.names())
+	if nil != e {
+		return fmt.Errorf("could not process zip files: %w", e)
+	}
+
+	return p.run(arc.header(), func() error {
+		if p.PrefixItem && 0 < arc.Prefix {
+			e := p.writePrefix(ctx, arc)
+			if nil != e {
+				return e
+			}
+		}
+		if p.Workers < 2 {
+			return p.processSequential(ctx, arc)
+		}
+		return p.processParallel(ctx, arc)
+	})
+}
+
+// run processes the items between the header and the trailer, and flushes the sink.
+func (p Processor) run(hdr ArchiveHeader, process func() error) error {
+	if p.Bracket {
+		e := p.writeHeader(hdr)
+		if nil != e {
+			return fmt.Errorf("could not process zip files: %w", e)
+		}
+	}
+
+	var e error = process()
+
+	if p.Bracket {
+		var status string = StatusComplete
+		if nil != e {
+			status = StatusAborted
+		}
+		te := p.writeTrailer(status)
+		if nil == e {
+			e = te
+		}
+	}
+
+	if nil != e {
+		return fmt.Errorf("could not process zip files: %w", e)
+	}
+	return FlushSink(p.Sink)
+}
+
+// items returns the indexes of the items to convert out of n.
+func (p Processor) items(n int) []int {
+	if nil != p.selected {
+		return p.selected
+	}
+	var items []int = make([]int, n)
+	for i := range items {
+		items[i] = i
+	}
+	return items
+}
+
+func (p Processor) processSequential(ctx context.Context, arc ZipArchive) error {
+	var files []*zip.File = arc.Files()
+	for _, i := range p.items(len(files)) {
+		var zfile *zip.File = files[i]
+		if nil != ctx.Err() {
+			return canceled(ctx)
+		}
+
+		var info ItemInfo = p.itemInfo(files, i)
+		if ss, ok := p.streamSink(); ok {
+			e := p.stream(ctx, info, zfile, ss)
+			if nil != e {
+				return fmt.Errorf("error processing file %s: %w", zfile.Name, e)
+			}
+			continue
+		}
+
+		item, e := p.convert(ctx, info, zfile)
+		if nil != e {
+			p.stats.failed++
+		} else {
+			e = p.write(item, zipEntry(zfile))
+		}
+		if nil != e {
+			return fmt.Errorf("error processing file %s: %w", zfile.Name, e)
+		}
+	}
+	return nil
+}
+
+type convertResult struct {
+	// position in the items
+	position int
+	item     converted
+	err      error
+}
+
+func (p Processor) processParallel(ctx context.Context, arc ZipArchive) error {
+	var files []*zip.File = arc.Files()
+	var limit int = p.maxInFlight()
+	var items []int = p.items(len(files))
+
+	// A token is held from dispatching an item until its blob is written,
+	// so the results never exceed the capacity of the channel.
+	tokens := make(chan struct{}, limit)
+	jobs := make(chan int)
+	results := make(chan convertResult, limit)
+	stop := make(chan struct{})
+
+	go func() {
+		defer close(jobs)
+		for position := range items {
+			select {
+			case tokens <- struct{}{}:
+			case <-stop:
+				return
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- position:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range p.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for position := range jobs {
+				var i int = items[position]
+				var info ItemInfo = p.itemInfo(files, i)
+				item, e := p.convert(ctx, info, files[i])
+				results <- convertResult{position: position, item: item, err: e}
+			}
+		}()
+	}
+
+	e := p.collect(ctx, files, items, results, tokens)
+	close(stop)
+	wg.Wait()
+
+	// the items converted after an abort
+	close(results)
+	for res := range results {
+		res.item.release()
+	}
+	return e
+}
+
+func (p Processor) collect(
+	ctx context.Context,
+	files []*zip.File,
+	items []int,
+	results <-chan convertResult,
+	tokens <-chan struct{},
+) error {
+	pending := map[int]converted{}
+	defer func() {
+		// the items not written when aborting
+		for _, item := range pending {
+			item.release()
+		}
+	}()
+	next := 0
+	for written := 0; written < len(items); {
+		var res convertResult
+		select {
+		case res = <-results:
+		case <-ctx.Done():
+			return canceled(ctx)
+		}
+		var zfile *zip.File = files[items[res.position]]
+		if nil != res.err {
+			p.stats.failed++
+			return fmt.Errorf("error processing file %s: %w", zfile.Name, res.err)
+		}
+
+		if p.Unordered {
+			e := p.write(res.item, zipEntry(zfile))
+			if nil != e {
+				return fmt.Errorf("error processing file %s: %w", zfile.Name, e)
+			}
+			<-tokens
+			written++
+			continue
+		}
+
+		pending[res.position] = res.item
+		for {
+			item, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			e := p.write(item, zipEntry(files[items[next]]))
+			if nil != e {
+				return fmt.Errorf("error processing file %s: %w", files[items[next]].Name, e)
+			}
+			<-tokens
+			next++
+			written++
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package zip2jsons_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"testing"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+	"github.com/takanoriyanagitani/go-zip2blobs2jsons"
+)
+
+func manyItems(n int) []string {
+	pairs := make([]string, 0, 2*n)
+	for i := range n {
+		pairs = append(pairs, fmt.Sprintf("item%03d.txt", i), fmt.Sprintf("content %d", i))
+	}
+	return pairs
+}
+
+func TestProcessor_Parallel(t *testing.T) {
+	t.Parallel()
+
+	var bldr bj.BlobBuilder = bj.BlobBuilder{MaxBytes: 1024}
+
+	t.Run("ordered output", func(t *testing.T) {
+		t.Parallel()
+
+		var sink zip2jsons.SliceSink
+		var proc zip2jsons.Processor = zip2jsons.Processor{
+			Sink:        &sink,
+			Builder:     bldr,
+			Workers:     4,
+			MaxInFlight: 3,
+		}
+		err := proc.Process(newTestArchive(t, manyItems(100)...))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(sink.Blobs) != 100 {
+			t.Fatalf("Expected 100 blobs, got %d", len(sink.Blobs))
+		}
+		for i, b := range sink.Blobs {
+			expected := fmt.Sprintf("item%03d.txt", i)
+			if b.Name != expected {
+				t.Fatalf("Expected %s at %d, got %s", expected, i, b.Name)
+			}
+		}
+	})
+
+	t.Run("unordered output", func(t *testing.T) {
+		t.Parallel()
+
+		var sink zip2jsons.SliceSink
+		var proc zip2jsons.Processor = zip2jsons.Processor{
+			Sink:      &sink,
+			Builder:   bldr,
+			Workers:   8,
+			Unordered: true,
+		}
+		err := proc.Process(newTestArchive(t, manyItems(100)...))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var names []string
+		for _, b := range sink.Blobs {
+			names = append(names, b.Name)
+		}
+		sort.Strings(names)
+		if len(names) != 100 || names[0] != "item000.txt" || names[99] != "item099.txt" {
+			t.Errorf("Unexpected blobs: %v", names)
+		}
+	})
+
+	t.Run("sink error stops workers", func(t *testing.T) {
+		t.Parallel()
+
+		expectedErr := io.ErrShortWrite
+		written := 0
+		var sink zip2jsons.BlobSinkFn = func(_ *bj.Blob) error {
+			written++
+			if written == 10 {
+				return expectedErr
+			}
+			return nil
+		}
+		var proc zip2jsons.Processor = zip2jsons.Processor{
+			Sink:    sink,
+			Builder: bldr,
+			Workers: 4,
+		}
+		err := proc.Process(newTestArchive(t, manyItems(100)...))
+		if !errors.Is(err, expectedErr) {
+			t.Errorf("Expected error %v, got %v", expectedErr, err)
+		}
+		if written != 10 {
+			t.Errorf("Expected 10 writes, got %d", written)
+		}
+	})
+}
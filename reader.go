@@ -3,6 +3,7 @@ package zip2jsons
 import (
 	"archive/zip"
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 
@@ -22,7 +23,7 @@ func (l FileLike) ToZip() (ZipArchive, error) {
 	if nil != e {
 		return ZipArchive{}, fmt.Errorf("%w: %v", ErrNewReader, e)
 	}
-	return ZipArchive{Reader: rdr}, nil
+	return ZipArchive{Reader: rdr, Size: l.Size}, nil
 }
 
 // ByteReader wraps a bytes.Reader.
@@ -55,7 +56,22 @@ func (b Buffer) ToFileLike() FileLike {
 }
 
 // Reader wraps an io.Reader.
-type Reader struct{ io.Reader }
+type Reader struct {
+	io.Reader
+
+	// SpillThreshold is the number of bytes kept in memory by ToFileLike and Process.
+	// Larger inputs are written to a temporary file. Zero keeps the whole input in memory.
+	SpillThreshold int64
+
+	// SpillDir is the directory of the temporary files. Defaults to os.TempDir.
+	SpillDir string
+
+	// Prefixed reads the zip files with FileLike.ToZipPrefixed, tolerating data before them.
+	Prefixed bool
+
+	// MaxDict is the SevenZipArchive.MaxDict of the 7z archives.
+	MaxDict int64
+}
 
 // ToLimited returns a new Reader that reads from r but stops after n bytes.
 func (r Reader) ToLimited(limit int64) io.Reader {
@@ -82,27 +98,66 @@ func (r Reader) toFileLike(limit int64) (FileLike, error) {
 	return buf.AsFileLike(), nil
 }
 
+// zip opens the zip file, tolerating data before it if Prefixed.
+func (r Reader) zip(f FileLike) (ZipArchive, error) {
+	if r.Prefixed {
+		return f.ToZipPrefixed()
+	}
+	return f.ToZip()
+}
+
+// ToZip reads the zip file from the Reader up to the limit into memory.
+// Use ToFileLike to spill large inputs to a temporary file.
+func (r Reader) ToZip(limit int64) (ZipArchive, error) { return r.toZip(limit) }
+
 func (r Reader) toZip(limit int64) (ZipArchive, error) {
 	f, e := r.toFileLike(limit)
 	if nil != e {
 		return ZipArchive{}, fmt.Errorf("could not convert to file-like: %w", e)
 	}
 
-	arc, e := f.ToZip()
+	arc, e := r.zip(f)
 	if nil != e {
 		return ZipArchive{}, fmt.Errorf("could not create zip archive: %w", e)
 	}
 	return arc, nil
 }
 
-// ToJsons reads a zip file from the Reader, processes its items into JSON blobs, and encodes them.
-func (r Reader) ToJsons(limit int64, enc JsonEncoder, bldr bj.BlobBuilder) error {
-	arc, e := r.toZip(limit)
+// ToJsons reads a zip file from the Reader, processes its items into blobs, and writes them to the sink.
+func (r Reader) ToJsons(limit int64, sink BlobSink, bldr bj.BlobBuilder) error {
+	return r.ProcessContext(context.Background(), limit, Processor{Sink: sink, Builder: bldr})
+}
+
+// ToJsonsContext is like ToJsons but stops once the context is done.
+func (r Reader) ToJsonsContext(ctx context.Context, limit int64, sink BlobSink, bldr bj.BlobBuilder) error {
+	return r.ProcessContext(ctx, limit, Processor{Sink: sink, Builder: bldr})
+}
+
+// Process reads a zip file from the Reader and converts its items using the processor.
+func (r Reader) Process(limit int64, p Processor) error {
+	return r.ProcessContext(context.Background(), limit, p)
+}
+
+// ProcessContext is like Process but stops once the context is done.
+// Inputs larger than SpillThreshold are spilled to a temporary file (see ToFileLike).
+func (r Reader) ProcessContext(ctx context.Context, limit int64, p Processor) error {
+	var cr Reader = r
+	cr.Reader = ContextReader{Reader: r.Reader, Context: ctx}
+	f, closer, e := cr.ToFileLike(limit)
+	if nil != ctx.Err() {
+		return canceled(ctx)
+	}
+	if nil != e {
+		return fmt.Errorf("could not convert reader to zip archive: %w", e)
+	}
+	defer closer.Close() //nolint:errcheck // only the spill file is removed
+
+	arc, e := r.zip(f)
 	if nil != e {
 		return fmt.Errorf("could not convert reader to zip archive: %w", e)
 	}
 
-	e = ProcessZipArchive(arc, enc, bldr)
+	e = p.ProcessContext(ctx, arc)
 	if nil != e {
 		return fmt.Errorf("could not process zip archive: %w", e)
 	}
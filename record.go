@@ -0,0 +1,142 @@
+package zip2jsons
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+)
+
+// recordField is a key/value pair of a blob in a binary format.
+type recordField struct {
+	key string
+	val any
+}
+
+// blobBody decodes the base64 body of the blob.
+func blobBody(b *bj.Blob) ([]byte, error) {
+	body, e := base64.StdEncoding.DecodeString(b.Body)
+	if nil != e {
+		return nil, fmt.Errorf("could not decode body of %s: %w", b.Name, e)
+	}
+	return body, nil
+}
+
+// blobFields returns the fields of the blob in the order of the JSON schema,
+// with the raw body in place of the base64 one.
+func blobFields(b *bj.Blob, body []byte) ([]recordField, error) {
+	var fields []recordField = []recordField{
+		{key: "name", val: b.Name},
+		{key: "content_type", val: b.ContentType},
+		{key: "content_encoding", val: b.ContentEncoding},
+		{key: "content_transfer_encoding", val: "binary"},
+		{key: "body", val: body},
+	}
+
+	if 0 < len(b.Metadata) {
+		var dec *json.Decoder = json.NewDecoder(bytes.NewReader(b.Metadata))
+		dec.UseNumber()
+		var meta any
+		e := dec.Decode(&meta)
+		if nil != e {
+			return nil, fmt.Errorf("could not decode metadata of %s: %w", b.Name, e)
+		}
+		fields = append(fields, recordField{key: "metadata", val: meta})
+	}
+	if nil != b.ContentLength {
+		fields = append(fields, recordField{key: "content_length", val: *b.ContentLength})
+	}
+	if nil != b.LastModified {
+		fields = append(fields, recordField{key: "last_modified", val: *b.LastModified})
+	}
+	return fields, nil
+}
+
+// valueWriter writes the values of a self-describing binary format.
+type valueWriter interface {
+	null()
+	boolean(bool)
+	integer(int64)
+	float(float64)
+	text(string)
+	binary([]byte)
+	timestamp(time.Time)
+	arrayHeader(int)
+	mapHeader(int)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	var keys []string = make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeValue writes the value decoded from JSON (or given by blobFields).
+func writeValue(w valueWriter, v any) error {
+	switch t := v.(type) {
+	case nil:
+		w.null()
+	case bool:
+		w.boolean(t)
+	case string:
+		w.text(t)
+	case []byte:
+		w.binary(t)
+	case int64:
+		w.integer(t)
+	case float64:
+		w.float(t)
+	case json.Number:
+		i, e := t.Int64()
+		if nil == e {
+			w.integer(i)
+			return nil
+		}
+		f, e := t.Float64()
+		if nil != e {
+			return fmt.Errorf("invalid number %s: %w", t, e)
+		}
+		w.float(f)
+	case time.Time:
+		w.timestamp(t)
+	case []any:
+		w.arrayHeader(len(t))
+		for _, item := range t {
+			e := writeValue(w, item)
+			if nil != e {
+				return e
+			}
+		}
+	case map[string]any:
+		w.mapHeader(len(t))
+		for _, key := range sortedKeys(t) {
+			w.text(key)
+			e := writeValue(w, t[key])
+			if nil != e {
+				return e
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported value type %T", v)
+	}
+	return nil
+}
+
+func writeFields(w valueWriter, fields []recordField) error {
+	w.mapHeader(len(fields))
+	for _, f := range fields {
+		w.text(f.key)
+		e := writeValue(w, f.val)
+		if nil != e {
+			return fmt.Errorf("could not write %s: %w", f.key, e)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,1094 @@
+package zip2jsons
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// sevenZipSignature is the magic of the 7z signature header.
+var sevenZipSignature = []byte{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c}
+
+const (
+	// sevenZipStartHeaderSize is the size of the signature header.
+	sevenZipStartHeaderSize = 32
+
+	// max7zHeaderSize limits the size of the (decoded) header held in memory.
+	max7zHeaderSize = 1 << 28
+
+	// sevenZipDefaultMaxDict is the default of SevenZipArchive.MaxDict.
+	sevenZipDefaultMaxDict = 1 << 28
+)
+
+// Property IDs of the 7z headers.
+const (
+	szIDEnd = iota
+	szIDHeader
+	szIDArchiveProperties
+	szIDAdditionalStreamsInfo
+	szIDMainStreamsInfo
+	szIDFilesInfo
+	szIDPackInfo
+	szIDUnpackInfo
+	szIDSubStreamsInfo
+	szIDSize
+	szIDCRC
+	szIDFolder
+	szIDCodersUnpackSize
+	szIDNumUnpackStream
+	szIDEmptyStream
+	szIDEmptyFile
+	szIDAnti
+	szIDName
+	szIDCTime
+	szIDATime
+	szIDMTime
+	szIDWinAttributes
+	szIDComment
+	szIDEncodedHeader
+)
+
+// Windows file attributes of the 7z items.
+const (
+	szAttrDirectory = 0x10
+
+	// szAttrUnixExtension marks the unix mode in the high 16 bits.
+	szAttrUnixExtension = 0x8000
+)
+
+// IsSevenZip reports whether the input starting with head is a 7z archive.
+func IsSevenZip(head []byte) bool { return bytes.HasPrefix(head, sevenZipSignature) }
+
+// SevenZipFile is an item of a 7z archive.
+type SevenZipFile struct {
+	// Name uses slashes and ends with a slash for the directories, as in zip archives.
+	Name     string
+	Size     uint64
+	Modified time.Time
+
+	// Attributes are the Windows attributes, with the unix mode in the high 16 bits if available.
+	Attributes uint32
+
+	CRC32  uint32
+	hasCRC bool
+
+	// folder is the index of the folder holding the content, or -1 for the items without content.
+	folder int
+
+	// offset is the position of the content in the decoded folder.
+	offset uint64
+}
+
+// IsDir reports whether the item is a directory.
+func (f SevenZipFile) IsDir() bool { return strings.HasSuffix(f.Name, "/") }
+
+// Mode returns the unix mode if recorded, or the mode implied by the Windows attributes.
+func (f SevenZipFile) Mode() fs.FileMode {
+	var mode fs.FileMode = 0o644
+	if 0 != f.Attributes&szAttrUnixExtension {
+		mode = fs.FileMode(f.Attributes>>16) & fs.ModePerm
+	}
+	if f.IsDir() {
+		if 0 == f.Attributes&szAttrUnixExtension {
+			mode = 0o755
+		}
+		mode |= fs.ModeDir
+	}
+	return mode
+}
+
+// Entry returns the item as an Entry.
+// The zip-only fields (compressed size, method and comment) are left empty.
+func (f SevenZipFile) Entry() Entry {
+	return Entry{
+		Name:          f.Name,
+		Size:          f.Size,
+		CRC32:         f.CRC32,
+		Modified:      f.Modified,
+		ExternalAttrs: f.Attributes,
+		Mode:          f.Mode().String(),
+	}
+}
+
+// SevenZipArchive is a 7z archive.
+// Only unencrypted headers are supported; the items are decoded by SevenZipArchive.Process7z.
+type SevenZipArchive struct {
+	FileLike
+
+	Files []SevenZipFile
+
+	// MaxDict limits the dictionary of the lzma and lzma2 coders, once capped at the size of the folder.
+	// The items of the folders needing a larger one fail with ErrUnsupported. Defaults to 256 MiB.
+	MaxDict int64
+
+	streams szStreams
+}
+
+func (a SevenZipArchive) maxDict() int64 {
+	if 0 < a.MaxDict {
+		return a.MaxDict
+	}
+	return sevenZipDefaultMaxDict
+}
+
+// To7z reads the headers of the 7z archive.
+func (l FileLike) To7z() (SevenZipArchive, error) {
+	hdr, e := read7zHeader(l)
+	if nil == e {
+		e = hdr.streams.check(l.Size)
+	}
+	if nil != e {
+		return SevenZipArchive{}, fmt.Errorf("could not read 7z header: %w", e)
+	}
+	return SevenZipArchive{FileLike: l, Files: hdr.files, streams: hdr.streams}, nil
+}
+
+func (a SevenZipArchive) names() []string {
+	var names []string = make([]string, 0, len(a.Files))
+	for _, f := range a.Files {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+// Entries returns the items without reading their content.
+func (a SevenZipArchive) Entries() []Entry {
+	var entries []Entry = make([]Entry, 0, len(a.Files))
+	for _, f := range a.Files {
+		entries = append(entries, f.Entry())
+	}
+	return entries
+}
+
+func invalid7z(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalid7z, fmt.Sprintf(format, args...))
+}
+
+// szCoder is a coder of a folder.
+type szCoder struct {
+	method uint64
+	numIn  int
+	numOut int
+	props  []byte
+}
+
+// szBindPair connects an input stream of a coder to an output stream of another coder.
+type szBindPair struct {
+	in  int
+	out int
+}
+
+// szFolder is a group of coders decoding one or more packed streams into one output stream.
+type szFolder struct {
+	coders    []szCoder
+	bindPairs []szBindPair
+
+	// packed are the input streams reading the packed streams.
+	packed []int
+
+	// firstPack is the index of the first packed stream of the folder.
+	firstPack int
+
+	// unpackSizes are the sizes of the output streams of the coders.
+	unpackSizes []uint64
+
+	crc    uint32
+	hasCRC bool
+
+	// numStreams is the number of items in the folder.
+	numStreams int
+}
+
+// mainOut returns the output stream not bound to another coder.
+func (f szFolder) mainOut() (int, error) {
+	for out := range f.unpackSizes {
+		if f.boundOut(out) < 0 {
+			return out, nil
+		}
+	}
+	return 0, invalid7z("folder without output")
+}
+
+func (f szFolder) boundOut(out int) int {
+	for i, bp := range f.bindPairs {
+		if bp.out == out {
+			return i
+		}
+	}
+	return -1
+}
+
+func (f szFolder) size() uint64 {
+	out, e := f.mainOut()
+	if nil != e {
+		return 0
+	}
+	return f.unpackSizes[out]
+}
+
+// szStreams are the packed streams and the folders decoding them.
+type szStreams struct {
+	packPos   uint64
+	packSizes []uint64
+	folders   []szFolder
+
+	// the sizes and the CRCs of the items in the folders
+	subSizes  []uint64
+	subCRCs   []uint32
+	subHasCRC []bool
+}
+
+// packOffset returns the offset of the packed stream in the archive.
+func (s szStreams) packOffset(i int) int64 {
+	var offset uint64 = sevenZipStartHeaderSize + s.packPos
+	for _, size := range s.packSizes[:i] {
+		offset += size
+	}
+	return int64(offset) //nolint:gosec // checked by check
+}
+
+// check verifies that the packed streams are within the archive.
+func (s szStreams) check(size int64) error {
+	var end uint64 = sevenZipStartHeaderSize + s.packPos
+	if end < s.packPos {
+		return invalid7z("pack position overflow")
+	}
+	for _, packSize := range s.packSizes {
+		end += packSize
+		if end < packSize {
+			return invalid7z("pack size overflow")
+		}
+	}
+	if uint64(size) < end { //nolint:gosec // not negative
+		return invalid7z("packed streams beyond the end of the archive")
+	}
+	return nil
+}
+
+// szReader reads the fields of a 7z header.
+type szReader struct {
+	b []byte
+}
+
+func (r *szReader) byte() (byte, error) {
+	if 0 == len(r.b) {
+		return 0, invalid7z("truncated header")
+	}
+	var b byte = r.b[0]
+	r.b = r.b[1:]
+	return b, nil
+}
+
+func (r *szReader) bytes(n uint64) ([]byte, error) {
+	if uint64(len(r.b)) < n {
+		return nil, invalid7z("truncated header")
+	}
+	var b []byte = r.b[:n]
+	r.b = r.b[n:]
+	return b, nil
+}
+
+// number reads a 7z NUMBER: the leading one bits of the first byte count the following bytes.
+func (r *szReader) number() (uint64, error) {
+	first, e := r.byte()
+	if nil != e {
+		return 0, e
+	}
+	var value uint64
+	var mask byte = 0x80
+	for i := range 8 {
+		if 0 == first&mask {
+			var high uint64 = uint64(first & (mask - 1))
+			return value | high<<(8*i), nil
+		}
+		b, e := r.byte()
+		if nil != e {
+			return 0, e
+		}
+		value |= uint64(b) << (8 * i)
+		mask >>= 1
+	}
+	return value, nil
+}
+
+// count reads a NUMBER of elements, each taking at least one byte of the header.
+func (r *szReader) count() (int, error) {
+	n, e := r.number()
+	if nil != e {
+		return 0, e
+	}
+	if uint64(len(r.b)) < n {
+		return 0, invalid7z("count %d beyond the header", n)
+	}
+	return int(n), nil //nolint:gosec // bounded by the header size
+}
+
+func (r *szReader) uint32() (uint32, error) {
+	b, e := r.bytes(4)
+	if nil != e {
+		return 0, e
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+func (r *szReader) uint64() (uint64, error) {
+	b, e := r.bytes(8)
+	if nil != e {
+		return 0, e
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+func (r *szReader) expect(id byte) error {
+	b, e := r.byte()
+	if nil != e {
+		return e
+	}
+	if id != b {
+		return invalid7z("expected property %#x, got %#x", id, b)
+	}
+	return nil
+}
+
+// bits reads a bit vector of n elements, most significant bit first.
+func (r *szReader) bits(n int) ([]bool, error) {
+	raw, e := r.bytes(uint64((n + 7) / 8)) //nolint:gosec // not negative
+	if nil != e {
+		return nil, e
+	}
+	var bits []bool = make([]bool, n)
+	for i := range bits {
+		bits[i] = 0 != raw[i/8]&(0x80>>(i%8))
+	}
+	return bits, nil
+}
+
+// defined reads the "all defined" byte, followed by a bit vector unless all are defined.
+func (r *szReader) defined(n int) ([]bool, error) {
+	all, e := r.byte()
+	if nil != e {
+		return nil, e
+	}
+	if 0 == all {
+		return r.bits(n)
+	}
+	var bits []bool = make([]bool, n)
+	for i := range bits {
+		bits[i] = true
+	}
+	return bits, nil
+}
+
+// digests reads the CRCs of n streams.
+func (r *szReader) digests(n int) ([]uint32, []bool, error) {
+	defined, e := r.defined(n)
+	if nil != e {
+		return nil, nil, e
+	}
+	var crcs []uint32 = make([]uint32, n)
+	for i, ok := range defined {
+		if !ok {
+			continue
+		}
+		crcs[i], e = r.uint32()
+		if nil != e {
+			return nil, nil, e
+		}
+	}
+	return crcs, defined, nil
+}
+
+func (r *szReader) packInfo(s *szStreams) error {
+	var e error
+	s.packPos, e = r.number()
+	if nil != e {
+		return e
+	}
+	n, e := r.count()
+	if nil != e {
+		return e
+	}
+	s.packSizes = make([]uint64, n)
+	for {
+		id, e := r.byte()
+		if nil != e {
+			return e
+		}
+		switch id {
+		case szIDEnd:
+			return nil
+		case szIDSize:
+			for i := range s.packSizes {
+				s.packSizes[i], e = r.number()
+				if nil != e {
+					return e
+				}
+			}
+		case szIDCRC:
+			_, _, e = r.digests(n)
+			if nil != e {
+				return e
+			}
+		default:
+			return invalid7z("unexpected property %#x in pack info", id)
+		}
+	}
+}
+
+func (r *szReader) coder() (szCoder, error) {
+	flags, e := r.byte()
+	if nil != e {
+		return szCoder{}, e
+	}
+	if 0 != flags&0xc0 {
+		return szCoder{}, invalid7z("unsupported coder flags %#x", flags)
+	}
+	id, e := r.bytes(uint64(flags & 0x0f))
+	if nil != e {
+		return szCoder{}, e
+	}
+	var c szCoder = szCoder{numIn: 1, numOut: 1}
+	for _, b := range id {
+		c.method = c.method<<8 | uint64(b)
+	}
+	if 0 != flags&0x10 {
+		c.numIn, e = r.count()
+		if nil == e {
+			c.numOut, e = r.count()
+		}
+		if nil != e {
+			return szCoder{}, e
+		}
+	}
+	if 0 != flags&0x20 {
+		size, e := r.number()
+		if nil != e {
+			return szCoder{}, e
+		}
+		c.props, e = r.bytes(size)
+		if nil != e {
+			return szCoder{}, e
+		}
+	}
+	return c, nil
+}
+
+func (r *szReader) folder() (szFolder, error) {
+	numCoders, e := r.count()
+	if nil != e {
+		return szFolder{}, e
+	}
+	if 0 == numCoders {
+		return szFolder{}, invalid7z("folder without coders")
+	}
+	var f szFolder = szFolder{coders: make([]szCoder, 0, numCoders), numStreams: 1}
+	var numIn, numOut int
+	for range numCoders {
+		c, e := r.coder()
+		if nil != e {
+			return szFolder{}, e
+		}
+		f.coders = append(f.coders, c)
+		numIn += c.numIn
+		numOut += c.numOut
+	}
+	if numOut < 1 || len(r.b) < numIn+numOut {
+		return szFolder{}, invalid7z("invalid number of coder streams")
+	}
+
+	f.bindPairs = make([]szBindPair, numOut-1)
+	for i := range f.bindPairs {
+		in, e := r.count()
+		if nil == e {
+			f.bindPairs[i].in = in
+			f.bindPairs[i].out, e = r.count()
+		}
+		if nil != e {
+			return szFolder{}, e
+		}
+	}
+
+	var numPacked int = numIn - len(f.bindPairs)
+	if numPacked < 1 {
+		return szFolder{}, invalid7z("folder without packed streams")
+	}
+	if 1 == numPacked {
+		for in := range numIn {
+			if !f.boundIn(in) {
+				f.packed = append(f.packed, in)
+				break
+			}
+		}
+		return f, nil
+	}
+	f.packed = make([]int, numPacked)
+	for i := range f.packed {
+		f.packed[i], e = r.count()
+		if nil != e {
+			return szFolder{}, e
+		}
+	}
+	return f, nil
+}
+
+func (f szFolder) boundIn(in int) bool {
+	for _, bp := range f.bindPairs {
+		if bp.in == in {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *szReader) unpackInfo(s *szStreams) error {
+	e := r.expect(szIDFolder)
+	if nil != e {
+		return e
+	}
+	n, e := r.count()
+	if nil != e {
+		return e
+	}
+	external, e := r.byte()
+	if nil != e {
+		return e
+	}
+	if 0 != external {
+		return invalid7z("external folders")
+	}
+	s.folders = make([]szFolder, n)
+	var firstPack int
+	for i := range s.folders {
+		s.folders[i], e = r.folder()
+		if nil != e {
+			return e
+		}
+		s.folders[i].firstPack = firstPack
+		firstPack += len(s.folders[i].packed)
+	}
+
+	e = r.expect(szIDCodersUnpackSize)
+	if nil != e {
+		return e
+	}
+	for i := range s.folders {
+		var numOut int
+		for _, c := range s.folders[i].coders {
+			numOut += c.numOut
+		}
+		s.folders[i].unpackSizes = make([]uint64, numOut)
+		for j := range s.folders[i].unpackSizes {
+			s.folders[i].unpackSizes[j], e = r.number()
+			if nil != e {
+				return e
+			}
+		}
+	}
+
+	for {
+		id, e := r.byte()
+		if nil != e {
+			return e
+		}
+		switch id {
+		case szIDEnd:
+			return nil
+		case szIDCRC:
+			crcs, defined, e := r.digests(n)
+			if nil != e {
+				return e
+			}
+			for i := range s.folders {
+				s.folders[i].crc, s.folders[i].hasCRC = crcs[i], defined[i]
+			}
+		default:
+			return invalid7z("unexpected property %#x in unpack info", id)
+		}
+	}
+}
+
+func (r *szReader) subStreamsInfo(s *szStreams) error {
+	id, e := r.byte()
+	if nil != e {
+		return e
+	}
+
+	var total int = len(s.folders)
+	if szIDNumUnpackStream == id {
+		total = 0
+		for i := range s.folders {
+			s.folders[i].numStreams, e = r.count()
+			if nil != e {
+				return e
+			}
+			total += s.folders[i].numStreams
+		}
+		if len(r.b) < total {
+			return invalid7z("number of streams beyond the header")
+		}
+		id, e = r.byte()
+		if nil != e {
+			return e
+		}
+	}
+
+	s.subSizes = make([]uint64, 0, total)
+	var hasSizes bool = szIDSize == id
+	for _, f := range s.folders {
+		if 0 == f.numStreams {
+			continue
+		}
+		var sum uint64
+		for j := 1; j < f.numStreams && hasSizes; j++ {
+			size, e := r.number()
+			if nil != e {
+				return e
+			}
+			sum += size
+			s.subSizes = append(s.subSizes, size)
+		}
+		if !hasSizes && 1 < f.numStreams {
+			return invalid7z("missing stream sizes")
+		}
+		if f.size() < sum {
+			return invalid7z("stream sizes beyond the folder size")
+		}
+		s.subSizes = append(s.subSizes, f.size()-sum)
+	}
+	if hasSizes {
+		id, e = r.byte()
+		if nil != e {
+			return e
+		}
+	}
+
+	// the streams alone in a folder with a CRC use the folder CRC
+	s.subCRCs = make([]uint32, 0, total)
+	s.subHasCRC = make([]bool, 0, total)
+	var unknown int
+	for _, f := range s.folders {
+		if 1 != f.numStreams || !f.hasCRC {
+			unknown += f.numStreams
+		}
+	}
+	for szIDEnd != id {
+		if szIDCRC != id {
+			return invalid7z("unexpected property %#x in substreams info", id)
+		}
+		crcs, defined, e := r.digests(unknown)
+		if nil != e {
+			return e
+		}
+		var k int
+		for _, f := range s.folders {
+			if 1 == f.numStreams && f.hasCRC {
+				s.subCRCs = append(s.subCRCs, f.crc)
+				s.subHasCRC = append(s.subHasCRC, true)
+				continue
+			}
+			s.subCRCs = append(s.subCRCs, crcs[k:k+f.numStreams]...)
+			s.subHasCRC = append(s.subHasCRC, defined[k:k+f.numStreams]...)
+			k += f.numStreams
+		}
+		id, e = r.byte()
+		if nil != e {
+			return e
+		}
+	}
+	return nil
+}
+
+// defaultSubStreams sets one item per folder when the substreams info is missing.
+func (s *szStreams) defaultSubStreams() {
+	if nil != s.subSizes {
+		return
+	}
+	s.subSizes = make([]uint64, 0, len(s.folders))
+	for _, f := range s.folders {
+		s.subSizes = append(s.subSizes, f.size())
+	}
+}
+
+func (s szStreams) subCRC(i int) (uint32, bool) {
+	if i < len(s.subHasCRC) {
+		return s.subCRCs[i], s.subHasCRC[i]
+	}
+	var k int
+	for _, f := range s.folders {
+		if i < k+f.numStreams {
+			return f.crc, 1 == f.numStreams && f.hasCRC
+		}
+		k += f.numStreams
+	}
+	return 0, false
+}
+
+func (r *szReader) streamsInfo() (szStreams, error) {
+	var s szStreams
+	for {
+		id, e := r.byte()
+		if nil != e {
+			return s, e
+		}
+		switch id {
+		case szIDEnd:
+			s.defaultSubStreams()
+			return s, nil
+		case szIDPackInfo:
+			e = r.packInfo(&s)
+		case szIDUnpackInfo:
+			e = r.unpackInfo(&s)
+		case szIDSubStreamsInfo:
+			e = r.subStreamsInfo(&s)
+		default:
+			e = invalid7z("unexpected property %#x in streams info", id)
+		}
+		if nil != e {
+			return s, e
+		}
+	}
+}
+
+// filetime converts a Windows FILETIME into a time.
+func filetime(ft uint64) time.Time {
+	const epoch = 11644473600                   // seconds from 1601 to 1970
+	var secs int64 = int64(ft/10000000) - epoch //nolint:gosec // at most 2^64/10^7
+	var nsecs int64 = int64(ft%10000000) * 100  //nolint:gosec // below 10^9
+	return time.Unix(secs, nsecs).UTC()
+}
+
+// szFileProps are the properties of the files in the files info.
+type szFileProps struct {
+	emptyStream []bool
+	emptyFile   []bool
+	anti        []bool
+	names       []string
+	mtimes      []uint64
+	hasMTime    []bool
+	attrs       []uint32
+	hasAttr     []bool
+}
+
+func (r *szReader) names(n int) ([]string, error) {
+	external, e := r.byte()
+	if nil != e {
+		return nil, e
+	}
+	if 0 != external {
+		return nil, invalid7z("external names")
+	}
+	var names []string = make([]string, 0, n)
+	var units []uint16
+	for 1 < len(r.b) {
+		var u uint16 = binary.LittleEndian.Uint16(r.b)
+		r.b = r.b[2:]
+		if 0 != u {
+			units = append(units, u)
+			continue
+		}
+		names = append(names, string(utf16.Decode(units)))
+		units = units[:0]
+	}
+	if n != len(names) {
+		return nil, invalid7z("%d names for %d files", len(names), n)
+	}
+	return names, nil
+}
+
+// values reads the defined values of n files; attributes use 4 bytes and times 8 bytes.
+func (r *szReader) values(n int, size int) ([]uint64, []bool, error) {
+	defined, e := r.defined(n)
+	if nil != e {
+		return nil, nil, e
+	}
+	external, e := r.byte()
+	if nil != e {
+		return nil, nil, e
+	}
+	if 0 != external {
+		return nil, nil, invalid7z("external file properties")
+	}
+	var values []uint64 = make([]uint64, n)
+	for i, ok := range defined {
+		if !ok {
+			continue
+		}
+		if 4 == size {
+			var v uint32
+			v, e = r.uint32()
+			values[i] = uint64(v)
+		} else {
+			values[i], e = r.uint64()
+		}
+		if nil != e {
+			return nil, nil, e
+		}
+	}
+	return values, defined, nil
+}
+
+func countTrue(bits []bool) int {
+	var n int
+	for _, b := range bits {
+		if b {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *szReader) fileProps(n int) (szFileProps, error) {
+	var props szFileProps
+	for {
+		id, e := r.byte()
+		if nil != e {
+			return props, e
+		}
+		if szIDEnd == id {
+			return props, nil
+		}
+		size, e := r.number()
+		if nil != e {
+			return props, e
+		}
+		raw, e := r.bytes(size)
+		if nil != e {
+			return props, e
+		}
+
+		var pr szReader = szReader{b: raw}
+		switch id {
+		case szIDEmptyStream:
+			props.emptyStream, e = pr.bits(n)
+		case szIDEmptyFile:
+			props.emptyFile, e = pr.bits(countTrue(props.emptyStream))
+		case szIDAnti:
+			props.anti, e = pr.bits(countTrue(props.emptyStream))
+		case szIDName:
+			props.names, e = pr.names(n)
+		case szIDMTime:
+			props.mtimes, props.hasMTime, e = pr.values(n, 8)
+		case szIDWinAttributes:
+			var attrs []uint64
+			attrs, props.hasAttr, e = pr.values(n, 4)
+			props.attrs = make([]uint32, 0, len(attrs))
+			for _, a := range attrs {
+				props.attrs = append(props.attrs, uint32(a)) //nolint:gosec // read from 4 bytes
+			}
+		default:
+			// times other than the modification time, comments and padding
+		}
+		if nil != e {
+			return props, e
+		}
+	}
+}
+
+// files reads the files info and assigns the items to the streams.
+func (r *szReader) files(s szStreams) ([]SevenZipFile, error) {
+	n, e := r.count()
+	if nil != e {
+		return nil, e
+	}
+	props, e := r.fileProps(n)
+	if nil != e {
+		return nil, e
+	}
+
+	var files []SevenZipFile = make([]SevenZipFile, 0, n)
+	var folder, stream, sub, empty int
+	var offset uint64
+	for i := range n {
+		var f SevenZipFile = SevenZipFile{folder: -1}
+		if i < len(props.names) {
+			f.Name = props.names[i]
+		}
+		if i < len(props.hasMTime) && props.hasMTime[i] {
+			f.Modified = filetime(props.mtimes[i])
+		}
+		if i < len(props.hasAttr) && props.hasAttr[i] {
+			f.Attributes = props.attrs[i]
+		}
+
+		if i < len(props.emptyStream) && props.emptyStream[i] {
+			var isFile, isAnti bool
+			if empty < len(props.emptyFile) {
+				isFile = props.emptyFile[empty]
+			}
+			if empty < len(props.anti) {
+				isAnti = props.anti[empty]
+			}
+			empty++
+			if isAnti {
+				continue
+			}
+			if !isFile || 0 != f.Attributes&szAttrDirectory {
+				f.Name = strings.TrimSuffix(f.Name, "/") + "/"
+			}
+			files = append(files, f)
+			continue
+		}
+
+		for folder < len(s.folders) && s.folders[folder].numStreams <= stream {
+			folder++
+			stream = 0
+			offset = 0
+		}
+		if len(s.folders) <= folder || len(s.subSizes) <= sub {
+			return nil, invalid7z("more files than streams")
+		}
+		f.folder, f.offset = folder, offset
+		f.Size = s.subSizes[sub]
+		f.CRC32, f.hasCRC = s.subCRC(sub)
+		files = append(files, f)
+		offset += f.Size
+		stream++
+		sub++
+	}
+	return files, nil
+}
+
+// szArchiveHeader is the decoded header of a 7z archive.
+type szArchiveHeader struct {
+	streams szStreams
+	files   []SevenZipFile
+}
+
+func (r *szReader) header() (szArchiveHeader, error) {
+	var hdr szArchiveHeader
+	for {
+		id, e := r.byte()
+		if nil != e {
+			return hdr, e
+		}
+		switch id {
+		case szIDEnd:
+			return hdr, nil
+		case szIDArchiveProperties:
+			e = r.skipProperties()
+		case szIDAdditionalStreamsInfo:
+			_, e = r.streamsInfo()
+		case szIDMainStreamsInfo:
+			hdr.streams, e = r.streamsInfo()
+		case szIDFilesInfo:
+			hdr.files, e = r.files(hdr.streams)
+		default:
+			e = invalid7z("unexpected property %#x in header", id)
+		}
+		if nil != e {
+			return hdr, e
+		}
+	}
+}
+
+func (r *szReader) skipProperties() error {
+	for {
+		id, e := r.byte()
+		if nil != e || szIDEnd == id {
+			return e
+		}
+		size, e := r.number()
+		if nil == e {
+			_, e = r.bytes(size)
+		}
+		if nil != e {
+			return e
+		}
+	}
+}
+
+// read7zHeader reads the signature header and the (possibly encoded) header.
+func read7zHeader(l FileLike) (szArchiveHeader, error) {
+	var start []byte = make([]byte, sevenZipStartHeaderSize)
+	_, e := l.ReadAt(start, 0)
+	if nil != e {
+		return szArchiveHeader{}, invalid7z("could not read signature header: %v", e)
+	}
+	if !IsSevenZip(start) {
+		return szArchiveHeader{}, invalid7z("missing signature")
+	}
+	if crc32.ChecksumIEEE(start[12:]) != binary.LittleEndian.Uint32(start[8:]) {
+		return szArchiveHeader{}, fmt.Errorf("%w: signature header", ErrChecksum)
+	}
+
+	var offset uint64 = binary.LittleEndian.Uint64(start[12:])
+	var size uint64 = binary.LittleEndian.Uint64(start[20:])
+	var crc uint32 = binary.LittleEndian.Uint32(start[28:])
+	var available uint64 = uint64(max(l.Size-sevenZipStartHeaderSize, 0)) //nolint:gosec // not negative
+	if available < offset || available-offset < size || max7zHeaderSize < size {
+		return szArchiveHeader{}, invalid7z("header of %d bytes at %d beyond the archive", size, offset)
+	}
+	if 0 == size {
+		// an empty archive
+		return szArchiveHeader{}, nil
+	}
+
+	var raw []byte = make([]byte, size)
+	_, e = l.ReadAt(raw, int64(sevenZipStartHeaderSize+offset)) //nolint:gosec // checked above
+	if nil != e {
+		return szArchiveHeader{}, invalid7z("could not read header: %v", e)
+	}
+	if crc32.ChecksumIEEE(raw) != crc {
+		return szArchiveHeader{}, fmt.Errorf("%w: header", ErrChecksum)
+	}
+
+	var r szReader = szReader{b: raw}
+	for {
+		id, e := r.byte()
+		if nil != e {
+			return szArchiveHeader{}, e
+		}
+		switch id {
+		case szIDHeader:
+			return r.header()
+		case szIDEncodedHeader:
+			raw, e = decodeHeader(l, &r)
+			if nil != e {
+				return szArchiveHeader{}, e
+			}
+			r = szReader{b: raw}
+		default:
+			return szArchiveHeader{}, invalid7z("unexpected property %#x at the start of the header", id)
+		}
+	}
+}
+
+// decodeHeader decodes the header packed into the streams described by the encoded header.
+func decodeHeader(l FileLike, r *szReader) ([]byte, error) {
+	s, e := r.streamsInfo()
+	if nil != e {
+		return nil, e
+	}
+	e = s.check(l.Size)
+	if nil != e {
+		return nil, e
+	}
+	if 1 != len(s.folders) {
+		return nil, invalid7z("encoded header in %d folders", len(s.folders))
+	}
+	var f szFolder = s.folders[0]
+	if max7zHeaderSize < f.size() {
+		return nil, invalid7z("encoded header of %d bytes", f.size())
+	}
+
+	// the dictionary is capped at the size of the header
+	rdr, e := s.folderReader(l, 0, max7zHeaderSize)
+	if nil != e {
+		return nil, fmt.Errorf("could not decode header: %w", e)
+	}
+	var raw []byte = make([]byte, f.size())
+	_, e = io.ReadFull(rdr, raw)
+	if nil != e {
+		return nil, invalid7z("could not decode header: %v", e)
+	}
+	if f.hasCRC && crc32.ChecksumIEEE(raw) != f.crc {
+		return nil, fmt.Errorf("%w: encoded header", ErrChecksum)
+	}
+	return raw, nil
+}
@@ -0,0 +1,211 @@
+package zip2jsons
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/ulikunitz/xz/lzma"
+)
+
+// Method IDs of the 7z coders.
+const (
+	szMethodCopy    = 0x00
+	szMethodDelta   = 0x03
+	szMethodLZMA2   = 0x21
+	szMethodLZMA    = 0x030101
+	szMethodBCJ     = 0x03030103
+	szMethodBCJ2    = 0x0303011b
+	szMethodPPMD    = 0x030401
+	szMethodDeflate = 0x040108
+	szMethodBZip2   = 0x040202
+	szMethodAES     = 0x06f10701
+)
+
+var szMethodNames = map[uint64]string{
+	szMethodCopy:    "copy",
+	szMethodDelta:   "delta",
+	szMethodLZMA2:   "lzma2",
+	szMethodLZMA:    "lzma",
+	szMethodBCJ:     "bcj",
+	szMethodBCJ2:    "bcj2",
+	szMethodPPMD:    "ppmd",
+	szMethodDeflate: "deflate",
+	szMethodBZip2:   "bzip2",
+	szMethodAES:     "aes",
+}
+
+func szMethodName(method uint64) string {
+	if name, ok := szMethodNames[method]; ok {
+		return name
+	}
+	return fmt.Sprintf("method(%#x)", method)
+}
+
+// lzma2DictSize decodes the dictionary size of the LZMA2 properties.
+func lzma2DictSize(p byte) (int64, error) {
+	if 40 < p {
+		return 0, invalid7z("lzma2 dictionary size %d", p)
+	}
+	if 40 == p {
+		return 0xffffffff, nil
+	}
+	return int64(2|p&1) << (p/2 + 11), nil
+}
+
+// dictCap caps the dictionary at the output size, as a larger one is never used.
+// A dictionary still larger than the limit is rejected.
+func dictCap(dictSize int64, size uint64, limit int64) (int, error) {
+	var capped int64 = dictSize
+	if size < uint64(capped) { //nolint:gosec // not negative
+		capped = int64(size) //nolint:gosec // below dictSize
+	}
+	if limit < capped {
+		return 0, fmt.Errorf("%w: 7z dictionary of %d bytes above the limit of %d", ErrUnsupported, capped, limit)
+	}
+	return int(max(capped, lzma.MinDictCap)), nil
+}
+
+// decoder returns the decoded output of the coder reading the input.
+// The dictionaries of the lzma coders are limited to dictMax bytes.
+func (c szCoder) decoder(in io.Reader, size uint64, dictMax int64) (io.Reader, error) {
+	switch c.method {
+	case szMethodCopy:
+		return in, nil
+	case szMethodLZMA:
+		if 5 != len(c.props) {
+			return nil, invalid7z("lzma properties of %d bytes", len(c.props))
+		}
+		dict, e := dictCap(int64(binary.LittleEndian.Uint32(c.props[1:])), size, dictMax)
+		if nil != e {
+			return nil, e
+		}
+		// the classic .lzma header: the properties, with the capped dictionary, and the uncompressed size
+		var hdr []byte = make([]byte, lzma.HeaderLen)
+		copy(hdr, c.props)
+		binary.LittleEndian.PutUint32(hdr[1:], uint32(dict)) //nolint:gosec // below the 32 bits dictionary size
+		binary.LittleEndian.PutUint64(hdr[5:], size)
+		r, e := lzma.NewReader(io.MultiReader(bytes.NewReader(hdr), in))
+		if nil != e {
+			return nil, fmt.Errorf("could not read lzma: %w", e)
+		}
+		return r, nil
+	case szMethodLZMA2:
+		if 1 != len(c.props) {
+			return nil, invalid7z("lzma2 properties of %d bytes", len(c.props))
+		}
+		dictSize, e := lzma2DictSize(c.props[0])
+		if nil != e {
+			return nil, e
+		}
+		dict, e := dictCap(dictSize, size, dictMax)
+		if nil != e {
+			return nil, e
+		}
+		var cfg lzma.Reader2Config = lzma.Reader2Config{DictCap: dict}
+		r, e := cfg.NewReader2(in)
+		if nil != e {
+			return nil, fmt.Errorf("could not read lzma2: %w", e)
+		}
+		return r, nil
+	case szMethodDeflate:
+		return flate.NewReader(in), nil
+	case szMethodBZip2:
+		return bzip2.NewReader(in), nil
+	default:
+		return nil, fmt.Errorf("%w: 7z coder %s", ErrUnsupported, szMethodName(c.method))
+	}
+}
+
+// outCoder returns the coder of the output stream.
+func (f szFolder) outCoder(out int) (int, error) {
+	for i, c := range f.coders {
+		if out < c.numOut {
+			return i, nil
+		}
+		out -= c.numOut
+	}
+	return 0, invalid7z("output stream %d out of range", out)
+}
+
+// firstIn returns the first input stream of the coder.
+func (f szFolder) firstIn(coder int) int {
+	var in int
+	for _, c := range f.coders[:coder] {
+		in += c.numIn
+	}
+	return in
+}
+
+// decode returns the output of the coder, reading its input from the packed streams
+// or from the output of the coder bound to it.
+func (f szFolder) decode(coder int, out int, packs []io.Reader, depth int, dictMax int64) (io.Reader, error) {
+	var c szCoder = f.coders[coder]
+	if 1 != c.numIn || 1 != c.numOut {
+		return nil, fmt.Errorf("%w: 7z coder %s with %d inputs", ErrUnsupported, szMethodName(c.method), c.numIn)
+	}
+	if len(f.coders) < depth {
+		return nil, invalid7z("coder cycle")
+	}
+
+	var in int = f.firstIn(coder)
+	var input io.Reader
+	for i, packed := range f.packed {
+		if packed == in {
+			input = packs[i]
+		}
+	}
+	for _, bp := range f.bindPairs {
+		if bp.in != in {
+			continue
+		}
+		bound, e := f.outCoder(bp.out)
+		if nil != e {
+			return nil, e
+		}
+		input, e = f.decode(bound, bp.out, packs, depth+1, dictMax)
+		if nil != e {
+			return nil, e
+		}
+	}
+	if nil == input {
+		return nil, invalid7z("unconnected coder input")
+	}
+
+	var size uint64 = f.unpackSizes[out]
+	dec, e := c.decoder(input, size, dictMax)
+	if nil != e {
+		return nil, e
+	}
+	return io.LimitReader(dec, int64(min(size, 1<<62))), nil //nolint:gosec // capped
+}
+
+// folderReader returns the decoded output of the folder.
+// An error wrapping ErrUnsupported is returned for the coders not supported,
+// and for the dictionaries larger than dictMax.
+func (s szStreams) folderReader(ra io.ReaderAt, i int, dictMax int64) (io.Reader, error) {
+	var f szFolder = s.folders[i]
+	if len(s.packSizes) < f.firstPack+len(f.packed) {
+		return nil, invalid7z("folder %d beyond the packed streams", i)
+	}
+	var packs []io.Reader = make([]io.Reader, 0, len(f.packed))
+	for j := range f.packed {
+		var pack int = f.firstPack + j
+		packs = append(packs, io.NewSectionReader(
+			ra, s.packOffset(pack), int64(s.packSizes[pack]), //nolint:gosec // checked by check
+		))
+	}
+
+	out, e := f.mainOut()
+	if nil != e {
+		return nil, e
+	}
+	coder, e := f.outCoder(out)
+	if nil != e {
+		return nil, e
+	}
+	return f.decode(coder, out, packs, 0, dictMax)
+}
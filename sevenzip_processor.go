@@ -0,0 +1,154 @@
+package zip2jsons
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"time"
+)
+
+// szItemReader reads the content of a 7z item and verifies its size and CRC at the end.
+type szItemReader struct {
+	r      io.Reader
+	name   string
+	size   uint64
+	read   uint64
+	crc    uint32
+	want   uint32
+	hasCRC bool
+}
+
+// Read implements io.Reader.
+func (i *szItemReader) Read(p []byte) (int, error) {
+	n, e := i.r.Read(p)
+	i.read += uint64(n) //nolint:gosec // not negative
+	i.crc = crc32.Update(i.crc, crc32.IEEETable, p[:n])
+	if !errors.Is(e, io.EOF) {
+		return n, e
+	}
+	if i.read != i.size {
+		return n, fmt.Errorf("%s: %w", i.name, io.ErrUnexpectedEOF)
+	}
+	if i.hasCRC && i.crc != i.want {
+		return n, fmt.Errorf("%w: %s", ErrChecksum, i.name)
+	}
+	return n, e
+}
+
+// szCursor is the position in the decoded output of a folder.
+// The items of a folder are compressed together, so they are read in order.
+type szCursor struct {
+	folder int
+	pos    uint64
+	r      io.Reader
+
+	// err is the error opening the folder, such as an unsupported coder.
+	err error
+}
+
+// content returns the content of the file, skipping the items before it in the folder.
+// The cursor is reopened when the file is in another folder.
+func (a SevenZipArchive) content(c *szCursor, f SevenZipFile) (io.Reader, error) {
+	if c.folder != f.folder || f.offset < c.pos {
+		c.r, c.err = a.streams.folderReader(a.ReaderAt, f.folder, a.maxDict())
+		c.folder = f.folder
+		c.pos = 0
+	}
+	if nil != c.err {
+		return nil, c.err
+	}
+
+	if c.pos < f.offset {
+		_, e := io.CopyN(io.Discard, c.r, int64(f.offset-c.pos)) //nolint:gosec // within the folder
+		if nil != e {
+			return nil, fmt.Errorf("could not skip to %s: %w", f.Name, e)
+		}
+	}
+	c.pos = f.offset + f.Size
+	return &szItemReader{
+		r:      io.LimitReader(c.r, int64(f.Size)), //nolint:gosec // within the folder
+		name:   f.Name,
+		size:   f.Size,
+		want:   f.CRC32,
+		hasCRC: f.hasCRC,
+	}, nil
+}
+
+// header returns the header record of the archive.
+func (a SevenZipArchive) header() ArchiveHeader {
+	return ArchiveHeader{
+		Entries: len(a.Files),
+		Size:    a.Size,
+		Format:  "7z",
+	}
+}
+
+// Process7z converts the items of the 7z archive and flushes the sink.
+func (p Processor) Process7z(arc SevenZipArchive) error {
+	return p.Process7zContext(context.Background(), arc)
+}
+
+// Process7zContext is like Process7z but stops once the context is done.
+//
+// The items are decoded sequentially, as the items of a folder are compressed together,
+// so Workers and Unordered are ignored.
+// The items of the folders using unsupported coders are written as ItemError records.
+func (p Processor) Process7zContext(ctx context.Context, arc SevenZipArchive) error {
+	p.stats = &archiveStats{started: time.Now()}
+
+	p, e := p.prepare(arc.names())
+	if nil != e {
+		return fmt.Errorf("could not process 7z files: %w", e)
+	}
+
+	return p.run(arc.header(), func() error {
+		return p.process7z(ctx, arc)
+	})
+}
+
+func (p Processor) process7z(ctx context.Context, arc SevenZipArchive) error {
+	var cursor szCursor = szCursor{folder: -1}
+	for _, i := range p.items(len(arc.Files)) {
+		var f SevenZipFile = arc.Files[i]
+		if nil != ctx.Err() {
+			return canceled(ctx)
+		}
+
+		var info ItemInfo = ItemInfo{Index: i, Total: len(arc.Files), Name: f.Name}
+		if NamesNormalize == p.Names {
+			info.Name = NormalizeName(f.Name)
+		}
+
+		var content io.Reader = bytes.NewReader(nil)
+		if 0 <= f.folder {
+			var e error
+			content, e = arc.content(&cursor, f)
+			if nil != cursor.err {
+				e = p.writeItemError(info.Name, e)
+				if nil != e {
+					return e
+				}
+				continue
+			}
+			if nil != e {
+				return fmt.Errorf("error processing file %s: %w", f.Name, e)
+			}
+		}
+
+		e := p.readItem(ctx, info, f.Modified, int64(f.Size), content, p.checks(i), f.Entry) //nolint:gosec // within the folder
+		if nil == e {
+			// the rest beyond Builder.MaxBytes, to verify the CRC
+			_, e = io.Copy(io.Discard, ContextReader{Reader: content, Context: ctx})
+			if nil != ctx.Err() {
+				e = canceled(ctx)
+			}
+		}
+		if nil != e {
+			return fmt.Errorf("error processing file %s: %w", f.Name, e)
+		}
+	}
+	return nil
+}
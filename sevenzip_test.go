@@ -0,0 +1,226 @@
+package zip2jsons_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+	"github.com/takanoriyanagitani/go-zip2blobs2jsons"
+)
+
+func read7z(t *testing.T, name string) []byte {
+	t.Helper()
+
+	dat, err := os.ReadFile("testdata.d/" + name)
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	return dat
+}
+
+func open7z(t *testing.T, dat []byte) zip2jsons.SevenZipArchive {
+	t.Helper()
+
+	arc, err := zip2jsons.FileLike{ReaderAt: bytes.NewReader(dat), Size: int64(len(dat))}.To7z()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return arc
+}
+
+func TestFileLike_To7z(t *testing.T) {
+	t.Parallel()
+
+	world := strings.Repeat("world ", 100)
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	for _, name := range []string{"copy.7z", "lzma.7z", "lzma2.7z"} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			arc := open7z(t, read7z(t, name))
+			entries := arc.Entries()
+			if len(entries) != 4 {
+				t.Fatalf("Expected 4 entries, got %d", len(entries))
+			}
+			if entries[1].Name != "dir/world.txt" || entries[1].Size != uint64(len(world)) {
+				t.Errorf("Unexpected entry: %+v", entries[1])
+			}
+			if entries[1].Mode != "-rw-------" || entries[2].Mode != "drwxr-xr-x" {
+				t.Errorf("Unexpected modes: %s, %s", entries[1].Mode, entries[2].Mode)
+			}
+
+			var sink zip2jsons.SliceSink
+			err := zip2jsons.Processor{Sink: &sink, Builder: bj.BlobBuilder{MaxBytes: 1024}}.Process7z(arc)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			expected := []struct{ name, body string }{
+				{"hello.txt", "hello, 7z\n"},
+				{"dir/world.txt", world},
+				{"dir/", ""},
+				{"empty.txt", ""},
+			}
+			if len(sink.Blobs) != len(expected) {
+				t.Fatalf("Expected %d blobs, got %d", len(expected), len(sink.Blobs))
+			}
+			for i, exp := range expected {
+				blb := sink.Blobs[i]
+				body, err := base64.StdEncoding.DecodeString(blb.Body)
+				if blb.Name != exp.name || string(body) != exp.body || err != nil {
+					t.Errorf("Expected %q, got %q: %q (%v)", exp.name, blb.Name, body, err)
+				}
+				if blb.LastModified == nil || !blb.LastModified.Equal(modified) {
+					t.Errorf("Unexpected modification time: %v", blb.LastModified)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessor_Process7z(t *testing.T) {
+	t.Parallel()
+
+	t.Run("truncated items of a solid folder", func(t *testing.T) {
+		t.Parallel()
+
+		var sink zip2jsons.SliceSink
+		p := zip2jsons.Processor{Sink: &sink, Builder: bj.BlobBuilder{MaxBytes: 4}}
+		err := p.Process7z(open7z(t, read7z(t, "lzma.7z")))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if sink.Blobs[0].Body != "aGVsbA==" || sink.Blobs[1].Body != "d29ybA==" {
+			t.Errorf("Unexpected bodies: %q, %q", sink.Blobs[0].Body, sink.Blobs[1].Body)
+		}
+	})
+
+	t.Run("skipped duplicates", func(t *testing.T) {
+		t.Parallel()
+
+		var sink zip2jsons.SliceSink
+		arc := open7z(t, read7z(t, "lzma.7z"))
+		arc.Files = append(arc.Files, arc.Files[0])
+		p := zip2jsons.Processor{Sink: &sink, Builder: bj.BlobBuilder{MaxBytes: 1024}, Duplicates: zip2jsons.DuplicatesLast}
+		err := p.Process7z(arc)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(sink.Blobs) != 4 || sink.Blobs[3].Name != "hello.txt" || sink.Blobs[3].Body != "aGVsbG8sIDd6Cg==" {
+			t.Errorf("Unexpected blobs: %d", len(sink.Blobs))
+		}
+	})
+
+	t.Run("unsupported coder", func(t *testing.T) {
+		t.Parallel()
+
+		var sink zip2jsons.SliceSink
+		p := zip2jsons.Processor{Sink: &sink, Builder: bj.BlobBuilder{MaxBytes: 1024}, Bracket: true}
+		err := p.Process7z(open7z(t, read7z(t, "ppmd.7z")))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(sink.Blobs) != 4 {
+			t.Fatalf("Expected 4 records, got %d", len(sink.Blobs))
+		}
+
+		var rec zip2jsons.ItemError
+		err = json.Unmarshal(sink.Blobs[1].Metadata, &rec)
+		if err != nil {
+			t.Fatalf("Failed to decode error record: %v", err)
+		}
+		if sink.Blobs[1].ContentType != zip2jsons.ContentTypeError || rec.Name != "a.txt" || !strings.Contains(rec.Error, "ppmd") {
+			t.Errorf("Unexpected error record: %+v", rec)
+		}
+		if sink.Blobs[2].Name != "b.txt" || sink.Blobs[2].Body != "c3RvcmVk" {
+			t.Errorf("Unexpected blob: %+v", sink.Blobs[2])
+		}
+
+		var trl zip2jsons.ArchiveTrailer
+		err = json.Unmarshal(sink.Blobs[3].Metadata, &trl)
+		if err != nil || trl.Items != 1 || trl.Failed != 1 {
+			t.Errorf("Unexpected trailer: %+v (%v)", trl, err)
+		}
+	})
+
+	for _, name := range []string{"lzma.7z", "lzma2.7z"} {
+		t.Run("dictionary above the limit of "+name, func(t *testing.T) {
+			t.Parallel()
+
+			var sink zip2jsons.SliceSink
+			arc := open7z(t, read7z(t, name))
+			arc.MaxDict = 100
+			err := zip2jsons.Processor{Sink: &sink, Builder: bj.BlobBuilder{MaxBytes: 1024}}.Process7z(arc)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			// the 600 bytes of dir/world.txt
+			var rec zip2jsons.ItemError
+			err = json.Unmarshal(sink.Blobs[1].Metadata, &rec)
+			if err != nil || sink.Blobs[1].ContentType != zip2jsons.ContentTypeError || !strings.Contains(rec.Error, "dictionary") {
+				t.Errorf("Unexpected error record: %+v (%v)", rec, err)
+			}
+		})
+	}
+
+	t.Run("corrupted content", func(t *testing.T) {
+		t.Parallel()
+
+		dat := read7z(t, "copy.7z")
+		dat[32] ^= 0xff
+		err := zip2jsons.Processor{
+			Sink:    &zip2jsons.SliceSink{},
+			Builder: bj.BlobBuilder{MaxBytes: 1024},
+		}.Process7z(open7z(t, dat))
+		if !errors.Is(err, zip2jsons.ErrChecksum) {
+			t.Errorf("Expected ErrChecksum, got %v", err)
+		}
+	})
+}
+
+func TestFileLike_To7zInvalid(t *testing.T) {
+	t.Parallel()
+
+	dat := read7z(t, "lzma2.7z")
+	tests := []struct {
+		name     string
+		input    []byte
+		expected error
+	}{
+		{"not 7z", []byte(strings.Repeat("x", 64)), zip2jsons.ErrInvalid7z},
+		{"truncated", dat[:len(dat)-8], zip2jsons.ErrInvalid7z},
+		{"corrupted header", append(bytes.Clone(dat[:len(dat)-1]), dat[len(dat)-1]^0xff), zip2jsons.ErrChecksum},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := zip2jsons.FileLike{ReaderAt: bytes.NewReader(tt.input), Size: int64(len(tt.input))}.To7z()
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestReader_ProcessAuto7z(t *testing.T) {
+	t.Parallel()
+
+	var sink zip2jsons.SliceSink
+	r := zip2jsons.Reader{Reader: bytes.NewReader(read7z(t, "lzma.7z"))}
+	err := r.ProcessAuto(1<<20, zip2jsons.Processor{Sink: &sink, Builder: bj.BlobBuilder{MaxBytes: 1024}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(sink.Blobs) != 4 || sink.Blobs[0].Name != "hello.txt" {
+		t.Errorf("Unexpected blobs: %d", len(sink.Blobs))
+	}
+}
@@ -0,0 +1,98 @@
+package zip2jsons
+
+import (
+	"fmt"
+	"io"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+)
+
+// BlobSink receives the blobs converted from zip items.
+type BlobSink interface {
+	Write(*bj.Blob) error
+}
+
+// BlobFlusher is implemented by sinks which buffer blobs before writing them out.
+type BlobFlusher interface {
+	Flush() error
+}
+
+// FlushSink flushes the sink if it implements BlobFlusher.
+func FlushSink(s BlobSink) error {
+	f, ok := s.(BlobFlusher)
+	if !ok {
+		return nil
+	}
+	e := f.Flush()
+	if nil != e {
+		return fmt.Errorf("could not flush sink: %w", e)
+	}
+	return nil
+}
+
+// CloseSink flushes the sink and closes it if it implements io.Closer.
+func CloseSink(s BlobSink) error {
+	e := FlushSink(s)
+	if nil != e {
+		return e
+	}
+	c, ok := s.(io.Closer)
+	if !ok {
+		return nil
+	}
+	e = c.Close()
+	if nil != e {
+		return fmt.Errorf("could not close sink: %w", e)
+	}
+	return nil
+}
+
+// Write implements BlobSink.
+func (j JsonEncoder) Write(b *bj.Blob) error { return j.EncodeBlob(b) }
+
+// BlobSinkFn adapts a function to the BlobSink interface.
+type BlobSinkFn func(*bj.Blob) error
+
+// Write implements BlobSink.
+func (f BlobSinkFn) Write(b *bj.Blob) error { return f(b) }
+
+// ChanSink sends each blob to the channel.
+type ChanSink chan<- *bj.Blob
+
+// Write implements BlobSink. It blocks until the blob is received.
+func (c ChanSink) Write(b *bj.Blob) error {
+	c <- b
+	return nil
+}
+
+// SliceSink collects the blobs in memory.
+type SliceSink struct{ Blobs []*bj.Blob }
+
+// Write implements BlobSink.
+func (s *SliceSink) Write(b *bj.Blob) error {
+	s.Blobs = append(s.Blobs, b)
+	return nil
+}
+
+// RawBlobSink is a BlobSink which encodes the item content itself.
+// The Processor prefers WriteRaw over Write for such sinks,
+// which saves building the base64 Body string of each blob.
+type RawBlobSink interface {
+	BlobSink
+
+	// WriteRaw writes the blob with the content as its body.
+	// The Body of the blob is empty, and the content is only valid during the call.
+	WriteRaw(b *bj.Blob, content []byte) error
+}
+
+// StreamBlobSink is a BlobSink which reads the item content while writing it.
+// The sequential Processor prefers WriteStream over WriteRaw and Write for such sinks,
+// so the memory per item does not depend on the item size.
+type StreamBlobSink interface {
+	BlobSink
+
+	// WriteStream writes the blob with the content read to its end as its body,
+	// and the number of bytes read as its content length; the blob itself is left as it is.
+	// The Body of the blob is empty. A failed read must not leave an unterminated record behind.
+	WriteStream(b *bj.Blob, content io.Reader) error
+}
@@ -0,0 +1,117 @@
+package zip2jsons_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+	"github.com/takanoriyanagitani/go-zip2blobs2jsons"
+)
+
+// newTestArchive creates an in-memory zip archive from name/content pairs.
+func newTestArchive(t testing.TB, pairs ...string) zip2jsons.ZipArchive {
+	t.Helper()
+
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		name := pairs[i]
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+		_, err = f.Write([]byte(pairs[i+1]))
+		if err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	err := w.Close()
+	if err != nil {
+		t.Fatalf("Failed to close zip writer: %v", err)
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Failed to create zip reader: %v", err)
+	}
+	return zip2jsons.ZipArchive{Reader: r}
+}
+
+type flushCounter struct {
+	zip2jsons.SliceSink
+
+	flushed int
+}
+
+func (f *flushCounter) Flush() error {
+	f.flushed++
+	return nil
+}
+
+func TestBlobSinks(t *testing.T) {
+	t.Parallel()
+
+	pairs := []string{"a.txt", "content1", "b.txt", "content2"}
+	var bldr bj.BlobBuilder = bj.BlobBuilder{MaxBytes: 1024}
+
+	t.Run("slice sink", func(t *testing.T) {
+		t.Parallel()
+
+		var sink zip2jsons.SliceSink
+		err := zip2jsons.ProcessZipArchive(newTestArchive(t, pairs...), &sink, bldr)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(sink.Blobs) != 2 {
+			t.Fatalf("Expected 2 blobs, got %d", len(sink.Blobs))
+		}
+		if sink.Blobs[0].Name != "a.txt" || sink.Blobs[1].Name != "b.txt" {
+			t.Errorf("Unexpected order: %s, %s", sink.Blobs[0].Name, sink.Blobs[1].Name)
+		}
+	})
+
+	t.Run("func sink error", func(t *testing.T) {
+		t.Parallel()
+
+		expectedErr := io.ErrShortWrite
+		var sink zip2jsons.BlobSinkFn = func(_ *bj.Blob) error { return expectedErr }
+		err := zip2jsons.ProcessZipArchive(newTestArchive(t, pairs...), sink, bldr)
+		if !errors.Is(err, expectedErr) {
+			t.Errorf("Expected error %v, got %v", expectedErr, err)
+		}
+	})
+
+	t.Run("chan sink", func(t *testing.T) {
+		t.Parallel()
+
+		ch := make(chan *bj.Blob, 2)
+		err := zip2jsons.ProcessZipArchive(newTestArchive(t, pairs...), zip2jsons.ChanSink(ch), bldr)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		close(ch)
+		var names []string
+		for b := range ch {
+			names = append(names, b.Name)
+		}
+		if len(names) != 2 {
+			t.Errorf("Expected 2 blobs, got %v", names)
+		}
+	})
+
+	t.Run("flushed after processing", func(t *testing.T) {
+		t.Parallel()
+
+		sink := &flushCounter{}
+		err := zip2jsons.ProcessZipArchive(newTestArchive(t, pairs...), sink, bldr)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if sink.flushed != 1 {
+			t.Errorf("Expected 1 flush, got %d", sink.flushed)
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package zip2jsons
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// spillFile is a temporary file removed on Close.
+type spillFile struct {
+	*os.File
+
+	// removed is true once the name is unlinked while the file is still open.
+	removed bool
+}
+
+func (s spillFile) Close() error {
+	e := s.File.Close()
+	if s.removed {
+		return e
+	}
+	return errors.Join(e, os.Remove(s.File.Name()))
+}
+
+// readHead reads up to n bytes.
+// Unlike bytes.Buffer, the buffer grows by doubling but never beyond n bytes.
+func readHead(r io.Reader, n int64) ([]byte, error) {
+	var buf []byte = make([]byte, 0, min(n, 64*1024))
+	for int64(len(buf)) < n {
+		if len(buf) == cap(buf) {
+			var grown []byte = make([]byte, len(buf), min(n, 2*int64(cap(buf))))
+			copy(grown, buf)
+			buf = grown
+		}
+
+		read, e := r.Read(buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+read]
+		if errors.Is(e, io.EOF) {
+			break
+		}
+		if nil != e {
+			return nil, e
+		}
+	}
+	return buf, nil
+}
+
+// spill writes the buffered head and the rest of the input to a temporary file.
+func (r Reader) spill(head []byte, rest io.Reader) (FileLike, io.Closer, error) {
+	f, e := os.CreateTemp(r.SpillDir, "zip2jsons-*.zip")
+	if nil != e {
+		return FileLike{}, nil, fmt.Errorf("could not create spill file: %w", e)
+	}
+
+	// unlinking the open file removes it even if the process dies; not possible on all platforms
+	var spilled spillFile = spillFile{File: f, removed: nil == os.Remove(f.Name())}
+
+	size, e := io.Copy(f, io.MultiReader(bytes.NewReader(head), rest))
+	if nil != e {
+		_ = spilled.Close()
+		return FileLike{}, nil, fmt.Errorf("could not write spill file: %w", e)
+	}
+	return FileLike{ReaderAt: f, Size: size}, spilled, nil
+}
+
+// ToFileLike reads the content of the Reader up to the limit.
+//
+// Inputs up to SpillThreshold bytes are kept in memory,
+// larger ones are written to a temporary file in SpillDir.
+// The returned Closer removes the temporary file and must be closed
+// once the FileLike is no longer used.
+func (r Reader) ToFileLike(limit int64) (FileLike, io.Closer, error) {
+	if r.SpillThreshold <= 0 || limit <= r.SpillThreshold {
+		f, e := r.toFileLike(limit)
+		return f, io.NopCloser(nil), e
+	}
+
+	var ltd io.Reader = r.ToLimited(limit)
+	head, e := readHead(ltd, r.SpillThreshold+1)
+	if nil != e {
+		return FileLike{}, nil, fmt.Errorf("could not copy to buffer: %w", e)
+	}
+	if int64(len(head)) <= r.SpillThreshold {
+		return ByteReader{Reader: bytes.NewReader(head)}.AsFileLike(), io.NopCloser(nil), nil
+	}
+	return r.spill(head, ltd)
+}
@@ -0,0 +1,91 @@
+package zip2jsons_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	bj "github.com/takanoriyanagitani/go-blob2json"
+	"github.com/takanoriyanagitani/go-zip2blobs2jsons"
+)
+
+func TestReader_ToFileLike(t *testing.T) {
+	t.Parallel()
+
+	content := newZipBytes(t, 200000, "a.txt", "hello")
+
+	read := func(t *testing.T, f zip2jsons.FileLike) []byte {
+		t.Helper()
+
+		got, err := io.ReadAll(io.NewSectionReader(f.ReaderAt, 0, f.Size))
+		if err != nil {
+			t.Fatalf("Failed to read: %v", err)
+		}
+		return got
+	}
+
+	t.Run("below the threshold", func(t *testing.T) {
+		t.Parallel()
+
+		r := zip2jsons.Reader{Reader: bytes.NewReader(content), SpillThreshold: int64(len(content)), SpillDir: t.TempDir()}
+		f, closer, err := r.ToFileLike(1 << 20)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer closer.Close() //nolint:errcheck
+
+		if _, ok := f.ReaderAt.(*bytes.Reader); !ok {
+			t.Errorf("Expected the input in memory, got %T", f.ReaderAt)
+		}
+		if !bytes.Equal(read(t, f), content) {
+			t.Errorf("Unexpected content")
+		}
+	})
+
+	t.Run("above the threshold", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		r := zip2jsons.Reader{Reader: bytes.NewReader(content), SpillThreshold: 1000, SpillDir: dir}
+		f, closer, err := r.ToFileLike(1 << 20)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if _, ok := f.ReaderAt.(*os.File); !ok {
+			t.Errorf("Expected the input in a file, got %T", f.ReaderAt)
+		}
+		if !bytes.Equal(read(t, f), content) {
+			t.Errorf("Unexpected content")
+		}
+
+		err = closer.Close()
+		if err != nil {
+			t.Fatalf("Failed to close: %v", err)
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil || len(entries) != 0 {
+			t.Errorf("Expected the spill file removed, got %v (%v)", entries, err)
+		}
+	})
+
+	t.Run("process", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		r := zip2jsons.Reader{Reader: bytes.NewReader(content), SpillThreshold: 1000, SpillDir: dir}
+		var sink zip2jsons.SliceSink
+		err := r.Process(1<<20, zip2jsons.Processor{Sink: &sink, Builder: bj.BlobBuilder{MaxBytes: 1024}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(sink.Blobs) != 2 {
+			t.Errorf("Expected 2 blobs, got %d", len(sink.Blobs))
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil || len(entries) != 0 {
+			t.Errorf("Expected the spill file removed, got %v (%v)", entries, err)
+		}
+	})
+}
@@ -0,0 +1,290 @@
+package zip2jsons
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// concatReaderAt reads the parts as one logical file.
+type concatReaderAt struct {
+	parts []FileLike
+
+	// offsets are the logical offsets of the parts.
+	offsets []int64
+}
+
+// ReadAt implements io.ReaderAt.
+func (c concatReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset: %d", off)
+	}
+	// the last part starting at or before the offset
+	var i int = sort.Search(len(c.offsets), func(i int) bool { return off < c.offsets[i] }) - 1
+
+	var n int
+	for ; 0 <= i && i < len(c.parts) && n < len(p); i++ {
+		var rel int64 = off + int64(n) - c.offsets[i]
+		var want int = int(min(int64(len(p)-n), c.parts[i].Size-rel)) //nolint:gosec // bounded by len(p)
+		if want <= 0 {
+			continue
+		}
+		got, e := c.parts[i].ReadAt(p[n:n+want], rel)
+		n += got
+		if nil != e && !(errors.Is(e, io.EOF) && got == want) {
+			return n, e
+		}
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// ConcatFileLike joins the parts into one logical file,
+// such as the pieces of an archive split byte by byte (name.zip.001, name.zip.002, ...).
+func ConcatFileLike(parts ...FileLike) FileLike {
+	var c concatReaderAt = concatReaderAt{
+		parts:   parts,
+		offsets: make([]int64, 0, len(parts)),
+	}
+	var size int64
+	for _, part := range parts {
+		c.offsets = append(c.offsets, size)
+		size += part.Size
+	}
+	return FileLike{ReaderAt: c, Size: size}
+}
+
+// SplitZipSegments returns the segments of a split zip archive in disk order:
+// the siblings name.z01, name.z02, ... followed by the .zip itself.
+// The .zip alone is returned if it has no siblings.
+func SplitZipSegments(zipPath string) ([]string, error) {
+	var ext string = filepath.Ext(zipPath)
+	var stem string = strings.TrimSuffix(zipPath, ext)
+	var prefix string = ".z"
+	if ".ZIP" == ext {
+		prefix = ".Z"
+	}
+
+	var segments []string
+	for i := 1; ; i++ {
+		var segment string = fmt.Sprintf("%s%s%02d", stem, prefix, i)
+		_, e := os.Stat(segment)
+		if errors.Is(e, fs.ErrNotExist) {
+			break
+		}
+		if nil != e {
+			return nil, fmt.Errorf("could not find the segments of %s: %w", zipPath, e)
+		}
+		segments = append(segments, segment)
+	}
+	return append(segments, zipPath), nil
+}
+
+// multiCloser closes all of the closers.
+type multiCloser []io.Closer
+
+func (m multiCloser) Close() error {
+	var errs []error
+	for _, c := range m {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// OpenSplitZip opens the segments of a split zip archive (see SplitZipSegments) as one zip file.
+// The returned Closer must be closed once the archive is no longer used.
+func OpenSplitZip(segments []string) (FileLike, io.Closer, error) {
+	var parts []FileLike = make([]FileLike, 0, len(segments))
+	var closers multiCloser = make(multiCloser, 0, len(segments))
+	for _, segment := range segments {
+		part, closer, e := OpenMmapFileLike(segment)
+		if nil != e {
+			_ = closers.Close()
+			return FileLike{}, nil, e
+		}
+		parts = append(parts, part)
+		closers = append(closers, closer)
+	}
+
+	f, e := JoinSplitZip(parts)
+	if nil != e {
+		_ = closers.Close()
+		return FileLike{}, nil, fmt.Errorf("could not join %s: %w", segments[len(segments)-1], e)
+	}
+	return f, closers, nil
+}
+
+// findEnd reads the end of central directory record, and the zip64 one if present, of the last segment.
+func findEnd(parts []FileLike) (zipEnd, error) {
+	rec, locator, _, e := endRecord(parts[len(parts)-1])
+	if nil != e {
+		return zipEnd{}, e
+	}
+	var end zipEnd = parseEnd(rec)
+	if !isZip64Locator(locator) {
+		return end, nil
+	}
+
+	var locDisk uint64 = uint64(binary.LittleEndian.Uint32(locator[4:]))
+	var locOff uint64 = binary.LittleEndian.Uint64(locator[8:])
+	if uint64(len(parts)) <= locDisk {
+		return zipEnd{}, fmt.Errorf("%w: zip64 end on disk %d of %d", ErrMissingSegment, locDisk, len(parts))
+	}
+	var z64 []byte = make([]byte, zip64EndLen)
+	_, e = parts[locDisk].ReadAt(z64, int64(locOff)) //nolint:gosec // checked by ReadAt
+	if nil != e {
+		return zipEnd{}, fmt.Errorf("%w: zip64 end of central directory not found", ErrNewReader)
+	}
+	e = end.zip64(z64)
+	if nil != e {
+		return zipEnd{}, e
+	}
+	return end, nil
+}
+
+// rewriteRecord returns the central directory record with the offset of the local header
+// made absolute and the disk number reset.
+func rewriteRecord(rec []byte, offsets []int64) ([]byte, error) {
+	var r centralRecord = parseRecord(rec)
+	if uint64(len(offsets)) <= r.disk {
+		return nil, fmt.Errorf("%w: %q on disk %d of %d", ErrMissingSegment, r.name, r.disk, len(offsets))
+	}
+	var offset uint64 = uint64(offsets[r.disk]) + r.offset //nolint:gosec // not negative
+
+	var out []byte = bytes.Clone(r.fixed)
+	binary.LittleEndian.PutUint16(out[34:], 0)
+	var z64 []byte
+	for _, v := range []struct {
+		val uint64
+		at  int
+	}{{r.usize, 24}, {r.csize, 20}, {offset, 42}} {
+		if v.val < 0xffffffff {
+			binary.LittleEndian.PutUint32(out[v.at:], uint32(v.val))
+			continue
+		}
+		binary.LittleEndian.PutUint32(out[v.at:], 0xffffffff)
+		z64 = binary.LittleEndian.AppendUint64(z64, v.val)
+	}
+
+	var extra []byte
+	if 0 < len(z64) {
+		extra = binary.LittleEndian.AppendUint16(extra, zip64ExtraID)
+		extra = binary.LittleEndian.AppendUint16(extra, uint16(len(z64))) //nolint:gosec // at most 24
+		extra = append(extra, z64...)
+	}
+	extra = append(extra, r.others...)
+	if 0xffff < len(extra) {
+		return nil, fmt.Errorf("%w: extra field of %q too large", ErrNewReader, r.name)
+	}
+	binary.LittleEndian.PutUint16(out[30:], uint16(len(extra))) //nolint:gosec // checked above
+
+	out = append(out, r.name...)
+	out = append(out, extra...)
+	return append(out, r.comment...), nil
+}
+
+// rewriteCentralDir rewrites the records of the central directory.
+func rewriteCentralDir(cd []byte, entries uint64, offsets []int64) ([]byte, error) {
+	var out []byte = make([]byte, 0, len(cd)+int(min(entries, uint64(len(cd)))))
+	e := eachRecord(cd, entries, func(rec []byte) error {
+		rewritten, e := rewriteRecord(rec, offsets)
+		out = append(out, rewritten...)
+		return e
+	})
+	return out, e
+}
+
+// appendEnd appends the end of central directory records of a single-disk archive,
+// with the zip64 ones if the values do not fit.
+func appendEnd(b []byte, entries, cdSize, cdOff uint64, comment []byte) []byte {
+	if 0xffff <= entries || 0xffffffff <= cdSize || 0xffffffff <= cdOff {
+		var z64Off uint64 = cdOff + cdSize
+		b = binary.LittleEndian.AppendUint32(b, sigZip64End)
+		b = binary.LittleEndian.AppendUint64(b, zip64EndLen-12)
+		b = binary.LittleEndian.AppendUint16(b, 45) // version made by
+		b = binary.LittleEndian.AppendUint16(b, 45) // version needed
+		b = binary.LittleEndian.AppendUint32(b, 0)
+		b = binary.LittleEndian.AppendUint32(b, 0)
+		b = binary.LittleEndian.AppendUint64(b, entries)
+		b = binary.LittleEndian.AppendUint64(b, entries)
+		b = binary.LittleEndian.AppendUint64(b, cdSize)
+		b = binary.LittleEndian.AppendUint64(b, cdOff)
+
+		b = binary.LittleEndian.AppendUint32(b, sigZip64Locator)
+		b = binary.LittleEndian.AppendUint32(b, 0)
+		b = binary.LittleEndian.AppendUint64(b, z64Off)
+		b = binary.LittleEndian.AppendUint32(b, 1)
+
+		entries = min(entries, 0xffff)
+		cdSize = min(cdSize, 0xffffffff)
+		cdOff = min(cdOff, 0xffffffff)
+	}
+	b = binary.LittleEndian.AppendUint32(b, sigEndOfCentralDir)
+	b = binary.LittleEndian.AppendUint32(b, 0) // this disk and the central directory disk
+	b = binary.LittleEndian.AppendUint16(b, uint16(entries))
+	b = binary.LittleEndian.AppendUint16(b, uint16(entries))
+	b = binary.LittleEndian.AppendUint32(b, uint32(cdSize))
+	b = binary.LittleEndian.AppendUint32(b, uint32(cdOff))
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(comment))) //nolint:gosec // read from 2 bytes
+	return append(b, comment...)
+}
+
+// JoinSplitZip joins the segments of a split zip archive, in disk order, into one zip file.
+//
+// The offsets in a split archive are relative to the disk holding the record,
+// so the central directory is rewritten with absolute offsets and appended to the concatenated
+// segments in place of the original one. The archive is used as is if it was not split.
+func JoinSplitZip(parts []FileLike) (FileLike, error) {
+	if 0 == len(parts) {
+		return FileLike{}, fmt.Errorf("%w: no segments", ErrMissingSegment)
+	}
+	var joined FileLike = ConcatFileLike(parts...)
+	var offsets []int64 = joined.ReaderAt.(concatReaderAt).offsets //nolint:forcetypeassert // created above
+
+	end, e := findEnd(parts)
+	if nil != e {
+		return FileLike{}, e
+	}
+	if 0 == end.disk {
+		// not split, or split byte by byte with absolute offsets
+		return joined, nil
+	}
+	if uint64(len(parts)) != end.disk+1 {
+		return FileLike{}, fmt.Errorf("%w: %d segments for %d disks", ErrMissingSegment, len(parts), end.disk+1)
+	}
+
+	if end.disk < end.cdDisk {
+		return FileLike{}, fmt.Errorf("%w: central directory on disk %d of %d", ErrMissingSegment, end.cdDisk, len(parts))
+	}
+
+	var cdStart uint64 = uint64(offsets[end.cdDisk]) + end.cdOff //nolint:gosec // not negative
+
+	if uint64(joined.Size) < cdStart || uint64(joined.Size)-cdStart < end.cdSize { //nolint:gosec // not negative
+		return FileLike{}, fmt.Errorf("%w: central directory beyond the archive", ErrNewReader)
+	}
+	var cd []byte = make([]byte, end.cdSize)
+	_, e = joined.ReadAt(cd, int64(cdStart)) //nolint:gosec // checked above
+	if nil != e {
+		return FileLike{}, fmt.Errorf("could not read the central directory: %w", e)
+	}
+	rewritten, e := rewriteCentralDir(cd, end.entries, offsets)
+	if nil != e {
+		return FileLike{}, e
+	}
+
+	var tail []byte = appendEnd(rewritten, end.entries, uint64(len(rewritten)), cdStart, end.comment)
+	var data FileLike = FileLike{
+		ReaderAt: io.NewSectionReader(joined.ReaderAt, 0, int64(cdStart)), //nolint:gosec // checked above
+		Size:     int64(cdStart),                                          //nolint:gosec // checked above
+	}
+	return ConcatFileLike(data, ByteReader{Reader: bytes.NewReader(tail)}.AsFileLike()), nil
+}
@@ -0,0 +1,153 @@
+package zip2jsons_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/takanoriyanagitani/go-zip2blobs2jsons"
+)
+
+func TestOpenSplitZip(t *testing.T) {
+	t.Parallel()
+
+	expected := map[string]string{
+		"a.bin": "b950ac87a04b754644d9088734a903a6da0d60265fc5586cf2e2a07c37c18d2d",
+		"b.txt": "029f8125cee27cadeac9de06769f1875abb8ca499ccca971add6210a0ca32b26",
+		"c.txt": "eccc70b16b90ea6f63ce6739dd44825b3949f31ee7710c07bfd2a74bb81dea66",
+	}
+
+	for _, name := range []string{"split.zip", "split64.zip"} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			segments, err := zip2jsons.SplitZipSegments("testdata.d/" + name)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(segments) != 2 || filepath.Ext(segments[0]) != ".z01" {
+				t.Fatalf("Unexpected segments: %v", segments)
+			}
+
+			f, closer, err := zip2jsons.OpenSplitZip(segments)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			defer closer.Close() //nolint:errcheck
+
+			arc, err := f.ToZip()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(arc.Files()) != len(expected) {
+				t.Fatalf("Expected %d files, got %d", len(expected), len(arc.Files()))
+			}
+			for _, zf := range arc.Files() {
+				rdr, err := zf.Open()
+				if err != nil {
+					t.Fatalf("Failed to open %s: %v", zf.Name, err)
+				}
+				// the CRC is verified at the end
+				h := sha256.New()
+				_, err = io.Copy(h, rdr)
+				if err != nil {
+					t.Fatalf("Failed to read %s: %v", zf.Name, err)
+				}
+				if hex.EncodeToString(h.Sum(nil)) != expected[zf.Name] {
+					t.Errorf("Unexpected content of %s", zf.Name)
+				}
+			}
+		})
+	}
+
+	t.Run("missing segment", func(t *testing.T) {
+		t.Parallel()
+
+		_, _, err := zip2jsons.OpenSplitZip([]string{"testdata.d/split.zip"})
+		if !errors.Is(err, zip2jsons.ErrMissingSegment) {
+			t.Errorf("Expected ErrMissingSegment, got %v", err)
+		}
+	})
+}
+
+func TestSplitZipSegments(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for _, name := range []string{"a.z01", "a.z02", "a.zip", "b.zip", "c.Z01", "c.ZIP"} {
+		err := os.WriteFile(filepath.Join(dir, name), nil, 0o600)
+		if err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		expected []string
+	}{
+		{"a.zip", []string{"a.z01", "a.z02", "a.zip"}},
+		{"b.zip", []string{"b.zip"}},
+		{"c.ZIP", []string{"c.Z01", "c.ZIP"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			segments, err := zip2jsons.SplitZipSegments(filepath.Join(dir, tt.name))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(segments) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, segments)
+			}
+			for i, segment := range segments {
+				if filepath.Base(segment) != tt.expected[i] {
+					t.Errorf("Expected %s, got %s", tt.expected[i], segment)
+				}
+			}
+		})
+	}
+}
+
+func TestConcatFileLike(t *testing.T) {
+	t.Parallel()
+
+	dat := newZipBytes(t, 4096, "hello.txt", "hello", "world.txt", "world")
+	var parts []zip2jsons.FileLike
+	for rest := dat; 0 < len(rest); rest = rest[min(len(rest), 1000):] {
+		parts = append(parts, zip2jsons.ByteReader{Reader: bytes.NewReader(rest[:min(len(rest), 1000)])}.AsFileLike())
+	}
+
+	f := zip2jsons.ConcatFileLike(parts...)
+	if f.Size != int64(len(dat)) {
+		t.Fatalf("Expected size %d, got %d", len(dat), f.Size)
+	}
+
+	buf := make([]byte, 2500)
+	n, err := f.ReadAt(buf, 500)
+	if err != nil || n != len(buf) || !bytes.Equal(buf, dat[500:3000]) {
+		t.Errorf("Unexpected read across the parts: %d (%v)", n, err)
+	}
+	n, err = f.ReadAt(buf, f.Size-10)
+	if !errors.Is(err, io.EOF) || n != 10 {
+		t.Errorf("Expected 10 bytes and EOF, got %d (%v)", n, err)
+	}
+
+	// split byte by byte, with the offsets left as they are
+	joined, err := zip2jsons.JoinSplitZip(parts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	arc, err := joined.ToZip()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(arc.Files()) == 0 {
+		t.Errorf("Expected files in the joined archive")
+	}
+}
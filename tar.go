@@ -0,0 +1,231 @@
+package zip2jsons
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Metadata keys of the tar header fields.
+const (
+	MetaMode = "mode"
+	MetaUid  = "uid"
+	MetaGid  = "gid"
+)
+
+// NewTarEntry creates an Entry from the header of a tar item.
+// The zip-only fields (compressed size, CRC, method, attributes and comment) are left empty.
+func NewTarEntry(h *tar.Header) Entry {
+	return Entry{
+		Name:     h.Name,
+		Size:     uint64(max(h.Size, 0)), //nolint:gosec // not negative
+		Modified: h.ModTime,
+		Mode:     h.FileInfo().Mode().String(),
+	}
+}
+
+// tarMetadata returns the mode (in octal), uid and gid of the tar item as metadata.
+func tarMetadata(h *tar.Header) map[string]string {
+	return map[string]string{
+		MetaMode: fmt.Sprintf("%04o", h.Mode&0o7777),
+		MetaUid:  strconv.Itoa(h.Uid),
+		MetaGid:  strconv.Itoa(h.Gid),
+	}
+}
+
+// tarState counts the names seen so far in a tar stream,
+// as they are (for the name findings) and folded (for the duplicate policy).
+type tarState struct {
+	names  map[string]int
+	folded map[string]int
+}
+
+// tarChecks returns the name checks and the tar metadata of the item,
+// or skip if the duplicate policy drops it.
+// As the stream is read once, the first copy of a duplicated name is not annotated.
+func (p Processor) tarChecks(s tarState, h *tar.Header) (map[string]string, bool, error) {
+	var checks map[string]string = tarMetadata(h)
+
+	var findings []NameFinding
+	if NamesNone != p.Names {
+		findings = CheckName(h.Name)
+		if NamesReject == p.Names && 0 < len(findings) {
+			return nil, false, fmt.Errorf("%w: %q (%s)", ErrUnsafeName, h.Name, JoinFindings(findings))
+		}
+	}
+
+	s.names[h.Name]++
+	if NamesNone != p.Names && 1 < s.names[h.Name] {
+		findings = append(findings, FindingDuplicate)
+	}
+
+	var key string = p.duplicateKey(h.Name)
+	var occurrence int = s.folded[key]
+	s.folded[key] = occurrence + 1
+	if 0 < occurrence {
+		switch p.Duplicates {
+		case DuplicatesFail:
+			return nil, false, fmt.Errorf("%w: %q appears more than once", ErrDuplicateName, h.Name)
+		case DuplicatesFirst:
+			return nil, true, nil
+		case DuplicatesAll:
+			checks[MetaOccurrence] = strconv.Itoa(occurrence)
+		default:
+		}
+	}
+
+	if 0 < len(findings) {
+		checks[MetaNameFindings] = JoinFindings(findings)
+	}
+	return checks, false, nil
+}
+
+// tarFormat returns the format of the header record.
+func tarFormat(c Compression) string {
+	if CompressionNone == c {
+		return "tar"
+	}
+	return "tar+" + string(c)
+}
+
+// ProcessTar converts the regular files and directories of a tar stream and flushes the sink.
+// The stream may be compressed with gzip, bzip2, xz or zstd, which is detected from its magic bytes.
+// The other entry types, such as links and devices, are skipped.
+func (p Processor) ProcessTar(r io.Reader) error {
+	return p.ProcessTarContext(context.Background(), r)
+}
+
+// ProcessTarContext is like ProcessTar but stops once the context is done.
+//
+// The items are converted sequentially while the stream is read, so Workers and Unordered are ignored.
+// ItemInfo.Total is -1, as the number of items is not known in advance.
+// DuplicatesLast is not supported, and DuplicatesAll only annotates the second and later copies.
+func (p Processor) ProcessTarContext(ctx context.Context, r io.Reader) error {
+	if DuplicatesLast == p.Duplicates {
+		return fmt.Errorf("%w: duplicate policy %s for tar", ErrUnsupported, p.Duplicates)
+	}
+	p.stats = &archiveStats{started: time.Now()}
+
+	var br *bufio.Reader = bufio.NewReader(ContextReader{Reader: r, Context: ctx})
+	head, _ := br.Peek(compressionMagic)
+	var c Compression = DetectCompression(head)
+	decompressed, closer, e := Decompress(br, c)
+	if nil != e {
+		return fmt.Errorf("could not process tar: %w", e)
+	}
+	defer closer.Close() //nolint:errcheck // the input is read only
+
+	var tr *bufio.Reader = bufio.NewReader(decompressed)
+	head, _ = tr.Peek(tarHeaderSize)
+	if !IsTar(head) {
+		if nil != ctx.Err() {
+			return canceled(ctx)
+		}
+		return fmt.Errorf("%w: %s input is not a tar archive", ErrUnsupported, tarFormat(c))
+	}
+
+	var hdr ArchiveHeader = ArchiveHeader{Entries: -1, Size: -1, Format: tarFormat(c)}
+	return p.run(hdr, func() error {
+		return p.processTarItems(ctx, tar.NewReader(tr))
+	})
+}
+
+func (p Processor) processTarItems(ctx context.Context, tr *tar.Reader) error {
+	var s tarState = tarState{names: map[string]int{}, folded: map[string]int{}}
+	for i := 0; ; i++ {
+		if nil != ctx.Err() {
+			return canceled(ctx)
+		}
+		h, e := tr.Next()
+		if errors.Is(e, io.EOF) {
+			return nil
+		}
+		if nil != ctx.Err() {
+			return canceled(ctx)
+		}
+		if nil != e {
+			return fmt.Errorf("could not read tar header: %w", e)
+		}
+
+		if tar.TypeReg != h.Typeflag && tar.TypeDir != h.Typeflag {
+			p.stats.skipped++
+			continue
+		}
+		checks, skip, e := p.tarChecks(s, h)
+		if nil != e {
+			return e
+		}
+		if skip {
+			p.stats.skipped++
+			continue
+		}
+
+		var info ItemInfo = ItemInfo{Index: i, Total: -1, Name: h.Name}
+		if NamesNormalize == p.Names {
+			info.Name = NormalizeName(h.Name)
+		}
+		e = p.readItem(ctx, info, h.ModTime, h.Size, tr, checks, func() Entry { return NewTarEntry(h) })
+		if nil != e {
+			return fmt.Errorf("error processing file %s: %w", h.Name, e)
+		}
+	}
+}
+
+// ProcessAuto reads a zip file, a 7z archive or a (compressed) tar stream from the Reader
+// and converts its items using the processor.
+// The format is detected from the magic bytes; other inputs are read as zip files.
+func (r Reader) ProcessAuto(limit int64, p Processor) error {
+	return r.ProcessAutoContext(context.Background(), limit, p)
+}
+
+// ProcessAutoContext is like ProcessAuto but stops once the context is done.
+// Unlike the zip and 7z archives, the tar streams are converted without buffering the input.
+func (r Reader) ProcessAutoContext(ctx context.Context, limit int64, p Processor) error {
+	var br *bufio.Reader = bufio.NewReader(r.ToLimited(limit))
+	head, _ := br.Peek(tarHeaderSize)
+	var buffered Reader = r
+	buffered.Reader = br
+	switch {
+	case IsSevenZip(head):
+		return buffered.process7z(ctx, limit, p)
+	case IsTarInput(head):
+		e := p.ProcessTarContext(ctx, br)
+		if nil != e {
+			return fmt.Errorf("could not process tar archive: %w", e)
+		}
+		return nil
+	default:
+		return buffered.ProcessContext(ctx, limit, p)
+	}
+}
+
+// process7z reads the 7z archive into memory or a spill file (see ToFileLike) and converts its items.
+func (r Reader) process7z(ctx context.Context, limit int64, p Processor) error {
+	var cr Reader = r
+	cr.Reader = ContextReader{Reader: r.Reader, Context: ctx}
+	f, closer, e := cr.ToFileLike(limit)
+	if nil != ctx.Err() {
+		return canceled(ctx)
+	}
+	if nil != e {
+		return fmt.Errorf("could not convert reader to 7z archive: %w", e)
+	}
+	defer closer.Close() //nolint:errcheck // only the spill file is removed
+
+	arc, e := f.To7z()
+	if nil != e {
+		return fmt.Errorf("could not convert reader to 7z archive: %w", e)
+	}
+	arc.MaxDict = r.MaxDict
+
+	e = p.Process7zContext(ctx, arc)
+	if nil != e {
+		return fmt.Errorf("could not process 7z archive: %w", e)
+	}
+	return nil
+}
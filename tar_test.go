@@ -0,0 +1,334 @@
+package zip2jsons_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	bj "github.com/takanoriyanagitani/go-blob2json"
+	"github.com/takanoriyanagitani/go-zip2blobs2jsons"
+	"github.com/ulikunitz/xz"
+)
+
+// newTarBytes creates a tar archive with the name/content pairs; names ending with "/" are directories.
+func newTarBytes(t *testing.T, pairs ...string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for i := 0; i < len(pairs); i += 2 {
+		hdr := &tar.Header{
+			Name:    pairs[i],
+			Mode:    0o640,
+			Uid:     1000,
+			Gid:     100,
+			Size:    int64(len(pairs[i+1])),
+			ModTime: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			Format:  tar.FormatPAX,
+		}
+		hdr.Typeflag = tar.TypeReg
+		if strings.HasSuffix(pairs[i], "/") {
+			hdr.Typeflag = tar.TypeDir
+			hdr.Mode = 0o755
+		}
+		err := tw.WriteHeader(hdr)
+		if err != nil {
+			t.Fatalf("Failed to write header: %v", err)
+		}
+		_, err = tw.Write([]byte(pairs[i+1]))
+		if err != nil {
+			t.Fatalf("Failed to write content: %v", err)
+		}
+	}
+	err := tw.Close()
+	if err != nil {
+		t.Fatalf("Failed to close tar: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func compressBytes(t *testing.T, c zip2jsons.Compression, dat []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	var err error
+	switch c {
+	case zip2jsons.CompressionGzip:
+		w = gzip.NewWriter(&buf)
+	case zip2jsons.CompressionXz:
+		w, err = xz.NewWriter(&buf)
+	case zip2jsons.CompressionZstd:
+		w, err = zstd.NewWriter(&buf)
+	default:
+		t.Fatalf("Unexpected compression %s", c)
+	}
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	_, err = w.Write(dat)
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		t.Fatalf("Failed to compress: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func blobMeta(t *testing.T, b *bj.Blob) map[string]string {
+	t.Helper()
+
+	var meta map[string]string
+	err := json.Unmarshal(b.Metadata, &meta)
+	if err != nil {
+		t.Fatalf("Failed to decode metadata: %v", err)
+	}
+	return meta
+}
+
+func TestDetectCompression(t *testing.T) {
+	t.Parallel()
+
+	tarball := newTarBytes(t, "a.txt", "hello")
+	tests := []struct {
+		name     string
+		input    []byte
+		expected zip2jsons.Compression
+		tar      bool
+	}{
+		{"tar", tarball, zip2jsons.CompressionNone, true},
+		{"gzip", compressBytes(t, zip2jsons.CompressionGzip, tarball), zip2jsons.CompressionGzip, false},
+		{"bzip2", []byte("BZh91AY&SY"), zip2jsons.CompressionBzip2, false},
+		{"xz", compressBytes(t, zip2jsons.CompressionXz, tarball), zip2jsons.CompressionXz, false},
+		{"zstd", compressBytes(t, zip2jsons.CompressionZstd, tarball), zip2jsons.CompressionZstd, false},
+		{"zip", newZipBytes(t, 0, "a.txt", "hello"), zip2jsons.CompressionNone, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := zip2jsons.DetectCompression(tt.input)
+			if got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+			if zip2jsons.IsTar(tt.input) != tt.tar {
+				t.Errorf("Expected IsTar %v", tt.tar)
+			}
+			if zip2jsons.IsTarInput(tt.input) != (tt.tar || zip2jsons.CompressionNone != tt.expected) {
+				t.Errorf("Unexpected IsTarInput")
+			}
+		})
+	}
+}
+
+func TestProcessor_ProcessTar(t *testing.T) {
+	t.Parallel()
+
+	tarball := newTarBytes(t, "dir/", "", "dir/a.txt", "hello", "b.txt", "world")
+	bzipped, err := os.ReadFile("testdata.d/hw.tar.bz2")
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		input  []byte
+		format string
+		names  []string
+		bodies []string
+	}{
+		{"plain", tarball, "tar", []string{"dir/", "dir/a.txt", "b.txt"}, []string{"", "hello", "world"}},
+		{
+			"gzip", compressBytes(t, zip2jsons.CompressionGzip, tarball), "tar+gzip",
+			[]string{"dir/", "dir/a.txt", "b.txt"}, []string{"", "hello", "world"},
+		},
+		{
+			"xz", compressBytes(t, zip2jsons.CompressionXz, tarball), "tar+xz",
+			[]string{"dir/", "dir/a.txt", "b.txt"}, []string{"", "hello", "world"},
+		},
+		{
+			"zstd", compressBytes(t, zip2jsons.CompressionZstd, tarball), "tar+zstd",
+			[]string{"dir/", "dir/a.txt", "b.txt"}, []string{"", "hello", "world"},
+		},
+		{"bzip2", bzipped, "tar+bzip2", []string{"dir/", "dir/hello.txt", "world.txt"}, []string{"", "hello", "world"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var sink zip2jsons.SliceSink
+			p := zip2jsons.Processor{Sink: &sink, Builder: bj.BlobBuilder{MaxBytes: 1024}, Bracket: true}
+			err := p.ProcessTar(bytes.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(sink.Blobs) != len(tt.names)+2 {
+				t.Fatalf("Expected %d records, got %d", len(tt.names)+2, len(sink.Blobs))
+			}
+
+			var hdr zip2jsons.ArchiveHeader
+			err = json.Unmarshal(sink.Blobs[0].Metadata, &hdr)
+			if err != nil {
+				t.Fatalf("Failed to decode header: %v", err)
+			}
+			if hdr.Format != tt.format || hdr.Entries != -1 || hdr.Size != -1 {
+				t.Errorf("Unexpected header: %+v", hdr)
+			}
+
+			for i, name := range tt.names {
+				blb := sink.Blobs[i+1]
+				if blb.Name != name {
+					t.Errorf("Expected name %q, got %q", name, blb.Name)
+				}
+				body, err := base64.StdEncoding.DecodeString(blb.Body)
+				if err != nil || string(body) != tt.bodies[i] {
+					t.Errorf("Expected body %q, got %q (%v)", tt.bodies[i], body, err)
+				}
+				meta := blobMeta(t, blb)
+				if meta[zip2jsons.MetaUid] != "1000" || meta[zip2jsons.MetaGid] != "100" {
+					t.Errorf("Unexpected owner: %v", meta)
+				}
+				if blb.LastModified == nil || !blb.LastModified.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)) {
+					t.Errorf("Unexpected modification time: %v", blb.LastModified)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessor_ProcessTarMetadata(t *testing.T) {
+	t.Parallel()
+
+	tarball := newTarBytes(t, "a.txt", "hello", "../b.txt", "x", "a.txt", "again")
+
+	t.Run("mode", func(t *testing.T) {
+		t.Parallel()
+
+		var sink zip2jsons.SliceSink
+		p := zip2jsons.Processor{Sink: &sink, Builder: bj.BlobBuilder{MaxBytes: 1024}}
+		err := p.ProcessTar(bytes.NewReader(tarball))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := blobMeta(t, sink.Blobs[0])[zip2jsons.MetaMode]; got != "0640" {
+			t.Errorf("Expected mode 0640, got %q", got)
+		}
+	})
+
+	t.Run("names and duplicates", func(t *testing.T) {
+		t.Parallel()
+
+		var sink zip2jsons.SliceSink
+		p := zip2jsons.Processor{
+			Sink:       &sink,
+			Builder:    bj.BlobBuilder{MaxBytes: 1024},
+			Names:      zip2jsons.NamesAnnotate,
+			Duplicates: zip2jsons.DuplicatesAll,
+		}
+		err := p.ProcessTar(bytes.NewReader(tarball))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := blobMeta(t, sink.Blobs[1])[zip2jsons.MetaNameFindings]; got != "parent" {
+			t.Errorf("Expected parent finding, got %q", got)
+		}
+		meta := blobMeta(t, sink.Blobs[2])
+		if meta[zip2jsons.MetaOccurrence] != "1" || meta[zip2jsons.MetaNameFindings] != "duplicate" {
+			t.Errorf("Unexpected duplicate metadata: %v", meta)
+		}
+	})
+
+	t.Run("first", func(t *testing.T) {
+		t.Parallel()
+
+		var sink zip2jsons.SliceSink
+		p := zip2jsons.Processor{Sink: &sink, Builder: bj.BlobBuilder{MaxBytes: 1024}, Duplicates: zip2jsons.DuplicatesFirst}
+		err := p.ProcessTar(bytes.NewReader(tarball))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(sink.Blobs) != 2 {
+			t.Errorf("Expected 2 blobs, got %d", len(sink.Blobs))
+		}
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		t.Parallel()
+
+		p := zip2jsons.Processor{Sink: &zip2jsons.SliceSink{}, Names: zip2jsons.NamesReject}
+		err := p.ProcessTar(bytes.NewReader(tarball))
+		if !errors.Is(err, zip2jsons.ErrUnsafeName) {
+			t.Errorf("Expected ErrUnsafeName, got %v", err)
+		}
+	})
+
+	t.Run("last", func(t *testing.T) {
+		t.Parallel()
+
+		p := zip2jsons.Processor{Sink: &zip2jsons.SliceSink{}, Duplicates: zip2jsons.DuplicatesLast}
+		err := p.ProcessTar(bytes.NewReader(tarball))
+		if !errors.Is(err, zip2jsons.ErrUnsupported) {
+			t.Errorf("Expected ErrUnsupported, got %v", err)
+		}
+	})
+}
+
+func TestReader_ProcessAuto(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{"zip", newZipBytes(t, 0, "a.txt", "hello")},
+		{"tar", newTarBytes(t, "a.txt", "hello")},
+		{"tar.gz", compressBytes(t, zip2jsons.CompressionGzip, newTarBytes(t, "a.txt", "hello"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			enc := zip2jsons.NewJsonStreamEncoder(&buf, true)
+			r := zip2jsons.Reader{Reader: bytes.NewReader(tt.input)}
+			err := r.ProcessAuto(1<<20, zip2jsons.Processor{Sink: enc, Builder: bj.BlobBuilder{MaxBytes: 1024}})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			// the zip starts with an empty large.bin
+			var blb bj.Blob
+			dec := json.NewDecoder(&buf)
+			for dec.More() {
+				err = dec.Decode(&blb)
+				if err != nil {
+					t.Fatalf("Failed to decode: %v", err)
+				}
+			}
+			body, err := base64.StdEncoding.DecodeString(blb.Body)
+			if blb.Name != "a.txt" || string(body) != "hello" {
+				t.Errorf("Unexpected blob %q: %q (%v)", blb.Name, body, err)
+			}
+		})
+	}
+
+	t.Run("compressed non-tar", func(t *testing.T) {
+		t.Parallel()
+
+		r := zip2jsons.Reader{Reader: bytes.NewReader(compressBytes(t, zip2jsons.CompressionGzip, []byte("hello")))}
+		err := r.ProcessAuto(1<<20, zip2jsons.Processor{Sink: &zip2jsons.SliceSink{}})
+		if !errors.Is(err, zip2jsons.ErrUnsupported) {
+			t.Errorf("Expected ErrUnsupported, got %v", err)
+		}
+	})
+}
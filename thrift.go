@@ -0,0 +1,86 @@
+package zip2jsons
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Thrift compact protocol types.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter writes structs with the Thrift compact protocol, as used by the Parquet metadata.
+type thriftWriter struct {
+	buf    bytes.Buffer
+	last   int16
+	parent []int16
+}
+
+func (t *thriftWriter) varint(v uint64) { t.buf.Write(binary.AppendUvarint(nil, v)) }
+
+func (t *thriftWriter) zigzag(v int64) { t.varint(uint64((v << 1) ^ (v >> 63))) }
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	var delta int16 = id - t.last
+	if 0 < delta && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.zigzag(int64(id))
+	}
+	t.last = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.zigzag(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.zigzag(v)
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.varint(uint64(len(s)))
+	t.buf.WriteString(s)
+}
+
+// list writes the header of a list field; the elements follow.
+func (t *thriftWriter) list(id int16, elem byte, n int) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.buf.WriteByte(byte(n)<<4 | elem)
+		return
+	}
+	t.buf.WriteByte(0xf0 | elem)
+	t.varint(uint64(n))
+}
+
+// begin starts a struct, either as a field or as a list element (id 0).
+func (t *thriftWriter) begin(id int16) {
+	if 0 != id {
+		t.field(id, thriftStruct)
+	}
+	t.parent = append(t.parent, t.last)
+	t.last = 0
+}
+
+func (t *thriftWriter) end() {
+	t.buf.WriteByte(0)
+	t.last = t.parent[len(t.parent)-1]
+	t.parent = t.parent[:len(t.parent)-1]
+}
+
+func (t *thriftWriter) listI32(v int32) { t.zigzag(int64(v)) }
+
+func (t *thriftWriter) listBinary(s string) {
+	t.varint(uint64(len(s)))
+	t.buf.WriteString(s)
+}
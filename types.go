@@ -2,19 +2,42 @@ package zip2jsons
 
 import (
 	"archive/zip"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"math"
 	"time"
 
 	bj "github.com/takanoriyanagitani/go-blob2json"
 )
 
 // ZipArchive wraps a zip.Reader for easier file processing.
-type ZipArchive struct{ *zip.Reader }
+type ZipArchive struct {
+	*zip.Reader
+
+	// Size is the size of the archive in bytes, if known.
+	Size int64
+
+	// Prefix is the length of the data before the first local header, such as the stub of
+	// a self-extracting archive. It is only set by FileLike.ToZipPrefixed.
+	Prefix int64
+
+	// prefix is the file starting with the data before the zip.
+	prefix io.ReaderAt
+}
 
 // Files returns the list of files within the zip archive.
 func (a ZipArchive) Files() []*zip.File { return a.Reader.File }
 
+func (a ZipArchive) names() []string {
+	var names []string = make([]string, 0, len(a.Reader.File))
+	for _, zfile := range a.Reader.File {
+		names = append(names, zfile.Name)
+	}
+	return names
+}
+
 // ProcessFiles iterates through each file in the zip archive and applies the given handler.
 func (a ZipArchive) ProcessFiles(
 	handler func(*zip.File) error,
@@ -41,8 +64,20 @@ func (i ZipItem) Modified() time.Time { return i.Header().Modified }
 // Name returns the name of the zip item.
 func (i ZipItem) Name() string { return i.Header().Name }
 
+// size returns the declared uncompressed size of the zip item.
+func (i ZipItem) size() int64 { return int64(min(i.UncompressedSize64, math.MaxInt64)) } //nolint:gosec // capped
+
 // ToBlob converts a ZipItem into a bj.Blob, applying content limits and base64 encoding.
 func (i ZipItem) ToBlob(builder bj.BlobBuilder) (*bj.Blob, error) {
+	return i.ToBlobContext(context.Background(), builder)
+}
+
+// ToBlobContext is like ToBlob but aborts the read once the context is done.
+func (i ZipItem) ToBlobContext(ctx context.Context, builder bj.BlobBuilder) (*bj.Blob, error) {
+	if nil != ctx.Err() {
+		return nil, canceled(ctx)
+	}
+
 	bldr := builder
 	var modified time.Time = i.Modified()
 	bldr.LastModified = &modified
@@ -54,14 +89,32 @@ func (i ZipItem) ToBlob(builder bj.BlobBuilder) (*bj.Blob, error) {
 	defer rc.Close() //nolint:errcheck// the "file" is read only
 
 	// if bldr.MaxBytes is unset(0; not initialized?), the blob will be empty
-	return bldr.NewBlobFromReader(rc, i.Name())
+	blb, e := bldr.NewBlobFromReader(ContextReader{Reader: rc, Context: ctx}, i.Name())
+	if nil != ctx.Err() {
+		return nil, canceled(ctx)
+	}
+	return blb, e
 }
 
 // JsonEncoder wraps a json.Encoder for encoding blobs.
-type JsonEncoder struct{ *json.Encoder }
+type JsonEncoder struct {
+	*json.Encoder
+
+	// NoEscapeHTML also keeps <, > and & of the (already encoded) metadata unescaped.
+	// It should match the SetEscapeHTML(false) of the Encoder.
+	NoEscapeHTML bool
+}
 
 // EncodeBlob encodes a bj.Blob to the underlying JSON encoder.
 func (j JsonEncoder) EncodeBlob(b *bj.Blob) error {
+	if j.NoEscapeHTML {
+		var e error
+		b, e = unescapeMetadataHTML(b)
+		if nil != e {
+			return e
+		}
+	}
+
 	e := j.Encoder.Encode(b)
 	if nil != e {
 		return fmt.Errorf("could not encode blob: %w", e)
@@ -6,4 +6,4 @@ tinygo \
 	-target=wasip1 \
 	-opt=z \
 	-no-debug \
-	./cmd/zip2blobs2jsons/main.go
+	./cmd/zip2blobs2jsons
@@ -1,8 +1,8 @@
 package zip2jsons
 
 import (
-	"archive/zip"
-	"fmt"
+	"context"
+	"maps"
 
 	bj "github.com/takanoriyanagitani/go-blob2json"
 )
@@ -17,35 +17,31 @@ type ZipBlobsBuilder struct {
 	ZipName string
 }
 
+// MetaZipName is the metadata key of the zip file name.
+const MetaZipName = "ZipName"
+
 // ToBuilder converts a ZipBlobsBuilder to a bj.BlobBuilder.
+// The ZipName is merged into a copy of the existing Metadata, replacing any MetaZipName entry.
 func (z ZipBlobsBuilder) ToBuilder() bj.BlobBuilder {
+	var meta map[string]string = make(map[string]string, len(z.BlobBuilder.Metadata)+1)
+	maps.Copy(meta, z.BlobBuilder.Metadata)
+	meta[MetaZipName] = z.ZipName
+
 	return bj.BlobBuilder{
 		ContentType:     z.BlobBuilder.ContentType,
 		ContentEncoding: z.BlobBuilder.ContentEncoding,
 		MaxBytes:        z.BlobBuilder.MaxBytes,
-		Metadata:        map[string]string{"ZipName": z.ZipName},
+		Metadata:        meta,
 		LastModified:    z.BlobBuilder.LastModified,
 	}
 }
 
-// ProcessZipArchive processes the files within a ZipArchive, converts each to a Blob, and encodes them to JSON.
-func ProcessZipArchive(arc ZipArchive, enc JsonEncoder, bldr bj.BlobBuilder) error {
-	e := arc.ProcessFiles(
-		func(zfile *zip.File) error {
-			zitem := ZipItem{File: zfile}
-			blb, e := zitem.ToBlob(bldr)
-			if nil != e {
-				return fmt.Errorf("could not convert zip item to blob: %w", e)
-			}
-			e = enc.EncodeBlob(blb)
-			if nil != e {
-				return fmt.Errorf("could not encode blob: %w", e)
-			}
-			return nil
-		},
-	)
-	if nil != e {
-		return fmt.Errorf("could not process zip files: %w", e)
-	}
-	return nil
+// ProcessZipArchive processes the files within a ZipArchive, converts each to a Blob, and writes them to the sink.
+func ProcessZipArchive(arc ZipArchive, sink BlobSink, bldr bj.BlobBuilder) error {
+	return Processor{Sink: sink, Builder: bldr}.Process(arc)
+}
+
+// ProcessZipArchiveContext is like ProcessZipArchive but stops once the context is done.
+func ProcessZipArchiveContext(ctx context.Context, arc ZipArchive, sink BlobSink, bldr bj.BlobBuilder) error {
+	return Processor{Sink: sink, Builder: bldr}.ProcessContext(ctx, arc)
 }
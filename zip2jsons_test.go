@@ -174,3 +174,21 @@ func TestProcessZipArchive(t *testing.T) {
 		}
 	})
 }
+
+func TestZipBlobsBuilder_ToBuilder(t *testing.T) {
+	t.Parallel()
+
+	var builder zip2jsons.ZipBlobsBuilder = zip2jsons.ZipBlobsBuilder{ZipName: "batch.zip"}
+	builder.Metadata = map[string]string{"source": "sys1", "ZipName": "ignored.zip"}
+
+	var bldr bj.BlobBuilder = builder.ToBuilder()
+	if bldr.Metadata["source"] != "sys1" {
+		t.Errorf("Expected caller metadata to be kept, got %v", bldr.Metadata)
+	}
+	if bldr.Metadata[zip2jsons.MetaZipName] != "batch.zip" {
+		t.Errorf("Expected ZipName 'batch.zip', got %v", bldr.Metadata)
+	}
+	if builder.Metadata["ZipName"] != "ignored.zip" {
+		t.Errorf("Expected caller map to be unchanged, got %v", builder.Metadata)
+	}
+}
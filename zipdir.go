@@ -0,0 +1,207 @@
+package zip2jsons
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Signatures and sizes of the zip records read by JoinSplitZip and ToZipPrefixed.
+const (
+	sigCentralDir      = 0x02014b50
+	sigEndOfCentralDir = 0x06054b50
+	sigZip64End        = 0x06064b50
+	sigZip64Locator    = 0x07064b50
+
+	centralDirLen    = 46
+	endOfCentralLen  = 22
+	zip64EndLen      = 56
+	zip64LocatorLen  = 20
+	maxZipCommentLen = 0xffff
+	maxZip64ExtLen   = 0xffff
+
+	zip64ExtraID = 0x0001
+)
+
+// zipEnd holds the fields of the end of central directory record.
+type zipEnd struct {
+	disk    uint64
+	cdDisk  uint64
+	entries uint64
+	cdSize  uint64
+	cdOff   uint64
+	comment []byte
+}
+
+// endRecord finds the end of central directory record at the end of the file.
+// It returns the record, with the 20 bytes before it for the zip64 locator, and its position in the file.
+func endRecord(f FileLike) (rec []byte, locator []byte, at int64, e error) {
+	var tailLen int64 = min(f.Size, endOfCentralLen+maxZipCommentLen+zip64LocatorLen)
+	var tail []byte = make([]byte, tailLen)
+	_, e = f.ReadAt(tail, f.Size-tailLen)
+	if nil != e && !errors.Is(e, io.EOF) {
+		return nil, nil, 0, fmt.Errorf("could not read the end of the archive: %w", e)
+	}
+
+	for i := len(tail) - endOfCentralLen; 0 <= i; i-- {
+		if sigEndOfCentralDir != binary.LittleEndian.Uint32(tail[i:]) {
+			continue
+		}
+		var commentLen int = int(binary.LittleEndian.Uint16(tail[i+20:]))
+		if len(tail) < i+endOfCentralLen+commentLen {
+			continue
+		}
+		if zip64LocatorLen <= i {
+			locator = tail[i-zip64LocatorLen : i]
+		}
+		return tail[i:], locator, f.Size - tailLen + int64(i), nil
+	}
+	return nil, nil, 0, fmt.Errorf("%w: end of central directory not found", ErrNewReader)
+}
+
+// parseEnd parses the end of central directory record.
+func parseEnd(rec []byte) zipEnd {
+	return zipEnd{
+		disk:    uint64(binary.LittleEndian.Uint16(rec[4:])),
+		cdDisk:  uint64(binary.LittleEndian.Uint16(rec[6:])),
+		entries: uint64(binary.LittleEndian.Uint16(rec[10:])),
+		cdSize:  uint64(binary.LittleEndian.Uint32(rec[12:])),
+		cdOff:   uint64(binary.LittleEndian.Uint32(rec[16:])),
+		comment: rec[endOfCentralLen : endOfCentralLen+int(binary.LittleEndian.Uint16(rec[20:]))],
+	}
+}
+
+// isZip64Locator reports whether the bytes before the end of central directory record are a zip64 locator.
+func isZip64Locator(locator []byte) bool {
+	return zip64LocatorLen == len(locator) && sigZip64Locator == binary.LittleEndian.Uint32(locator)
+}
+
+// zip64EndBefore finds the zip64 end of central directory record ending where the locator starts,
+// from the size field of the record as it may have an extensible data sector (of at most maxZip64ExtLen bytes).
+// It returns the record and its position in the file.
+func zip64EndBefore(f FileLike, locatorAt int64) (rec []byte, at int64, e error) {
+	var windowLen int64 = min(locatorAt, zip64EndLen+maxZip64ExtLen)
+	var window []byte = make([]byte, windowLen)
+	_, e = f.ReadAt(window, locatorAt-windowLen)
+	if nil != e {
+		return nil, 0, fmt.Errorf("could not read the zip64 end of central directory: %w", e)
+	}
+
+	for i := len(window) - zip64EndLen; 0 <= i; i-- {
+		if sigZip64End != binary.LittleEndian.Uint32(window[i:]) {
+			continue
+		}
+		// the size field excludes the signature and itself
+		if uint64(len(window)-i-12) != binary.LittleEndian.Uint64(window[i+4:]) { //nolint:gosec // not negative
+			continue
+		}
+		return window[i:], locatorAt - windowLen + int64(i), nil
+	}
+	return nil, 0, fmt.Errorf("%w: zip64 end of central directory not found", ErrNewReader)
+}
+
+// zip64 replaces the fields with those of the zip64 end of central directory record.
+func (z *zipEnd) zip64(rec []byte) error {
+	if len(rec) < zip64EndLen || sigZip64End != binary.LittleEndian.Uint32(rec) {
+		return fmt.Errorf("%w: zip64 end of central directory not found", ErrNewReader)
+	}
+	z.disk = uint64(binary.LittleEndian.Uint32(rec[16:]))
+	z.cdDisk = uint64(binary.LittleEndian.Uint32(rec[20:]))
+	z.entries = binary.LittleEndian.Uint64(rec[32:])
+	z.cdSize = binary.LittleEndian.Uint64(rec[40:])
+	z.cdOff = binary.LittleEndian.Uint64(rec[48:])
+	return nil
+}
+
+// zip64Fields are the fields of the zip64 extra which replace the saturated fields of a central directory record.
+type zip64Fields struct {
+	usize, csize, offset, disk uint64
+}
+
+// centralRecord is a central directory record with the zip64 fields resolved.
+type centralRecord struct {
+	zip64Fields
+
+	fixed   []byte
+	name    []byte
+	comment []byte
+
+	// others are the extra fields other than the zip64 one.
+	others []byte
+}
+
+// parseRecord parses the central directory record.
+func parseRecord(rec []byte) centralRecord {
+	var nameLen int = int(binary.LittleEndian.Uint16(rec[28:]))
+	var extraLen int = int(binary.LittleEndian.Uint16(rec[30:]))
+	var commentLen int = int(binary.LittleEndian.Uint16(rec[32:]))
+	var extra []byte = rec[centralDirLen+nameLen : centralDirLen+nameLen+extraLen]
+
+	var r centralRecord = centralRecord{
+		zip64Fields: zip64Fields{
+			usize:  uint64(binary.LittleEndian.Uint32(rec[24:])),
+			csize:  uint64(binary.LittleEndian.Uint32(rec[20:])),
+			offset: uint64(binary.LittleEndian.Uint32(rec[42:])),
+			disk:   uint64(binary.LittleEndian.Uint16(rec[34:])),
+		},
+		fixed:   rec[:centralDirLen],
+		name:    rec[centralDirLen : centralDirLen+nameLen],
+		comment: rec[centralDirLen+nameLen+extraLen : centralDirLen+nameLen+extraLen+commentLen],
+	}
+	var others bytes.Buffer
+	for 4 <= len(extra) {
+		var id uint16 = binary.LittleEndian.Uint16(extra)
+		var size int = int(binary.LittleEndian.Uint16(extra[2:]))
+		if len(extra) < 4+size {
+			break
+		}
+		var field []byte = extra[4 : 4+size]
+		if zip64ExtraID != id {
+			others.Write(extra[:4+size])
+			extra = extra[4+size:]
+			continue
+		}
+		for _, v := range []struct {
+			val  *uint64
+			max  uint64
+			size int
+		}{{&r.usize, 0xffffffff, 8}, {&r.csize, 0xffffffff, 8}, {&r.offset, 0xffffffff, 8}, {&r.disk, 0xffff, 4}} {
+			if *v.val != v.max || len(field) < v.size {
+				continue
+			}
+			if 8 == v.size {
+				*v.val = binary.LittleEndian.Uint64(field)
+			} else {
+				*v.val = uint64(binary.LittleEndian.Uint32(field))
+			}
+			field = field[v.size:]
+		}
+		extra = extra[4+size:]
+	}
+	r.others = others.Bytes()
+	return r
+}
+
+// eachRecord calls the function with each of the records of the central directory.
+func eachRecord(cd []byte, entries uint64, f func(rec []byte) error) error {
+	for range entries {
+		if len(cd) < centralDirLen || sigCentralDir != binary.LittleEndian.Uint32(cd) {
+			return fmt.Errorf("%w: invalid central directory record", ErrNewReader)
+		}
+		var recLen int = centralDirLen +
+			int(binary.LittleEndian.Uint16(cd[28:])) +
+			int(binary.LittleEndian.Uint16(cd[30:])) +
+			int(binary.LittleEndian.Uint16(cd[32:]))
+		if len(cd) < recLen {
+			return fmt.Errorf("%w: truncated central directory", ErrNewReader)
+		}
+		e := f(cd[:recLen])
+		if nil != e {
+			return e
+		}
+		cd = cd[recLen:]
+	}
+	return nil
+}